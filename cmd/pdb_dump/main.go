@@ -3,8 +3,12 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/kr/pretty"
 	"github.com/mewkiz/pkg/term"
@@ -22,49 +26,204 @@ var (
 )
 
 func main() {
+	var (
+		// Report error for unsupported streams.
+		strict bool
+		// Log debug messages of the pdb package.
+		verbose bool
+		// Recover from parse errors, reporting diagnostics.
+		recoverMode bool
+		// Memory-map PDB files instead of reading them into memory.
+		useMmap bool
+		// Output directory of injected source files.
+		extractSourcesDir string
+	)
+	flag.BoolVar(&strict, "strict", false, "report error for unsupported streams")
+	flag.BoolVar(&verbose, "v", false, "log debug messages of the pdb package")
+	flag.BoolVar(&recoverMode, "recover", false, "recover from parse errors, reporting diagnostics")
+	flag.BoolVar(&useMmap, "mmap", false, "memory-map PDB files instead of reading them into memory")
+	flag.StringVar(&extractSourcesDir, "extract-sources", "", "write injected source files to the given output directory")
 	flag.Parse()
+	opts := &pdb.ParseOptions{
+		Warn:    log.New(os.Stderr, term.RedBold("pdb:")+" ", 0),
+		Strict:  strict,
+		Recover: recoverMode,
+	}
+	if verbose {
+		opts.Debug = log.New(os.Stderr, term.CyanBold("pdb:")+" ", 0)
+	}
 	for _, pdbPath := range flag.Args() {
-		if err := pdbDump(pdbPath); err != nil {
+		if len(extractSourcesDir) > 0 {
+			if err := extractSources(pdbPath, extractSourcesDir, opts, useMmap); err != nil {
+				log.Fatalf("%+v", err)
+			}
+			continue
+		}
+		if err := pdbDump(pdbPath, opts, useMmap); err != nil {
 			log.Fatalf("%+v", err)
 		}
 	}
 }
 
-// pdbDump dumps the contents of the given PDB file.
-func pdbDump(pdbPath string) error {
-	file, err := pdb.ParseFile(pdbPath)
+// pdbDump dumps the contents of the given PDB file, optionally memory-mapping
+// the file.
+func pdbDump(pdbPath string, opts *pdb.ParseOptions, useMmap bool) error {
+	file, err := parseFile(pdbPath, opts, useMmap)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	file.Data = nil // TODO: remove
-	/*
-		for pageNum := 0; pageNum < int(file.FileHdr.NPages); pageNum++ {
-			fmt.Printf("pageNum: %d, free: %v\n", pageNum, file.FreePageMap.IsFree(pageNum))
+	defer file.Close()
+	pretty.Println(file.FileHdr)
+	fmt.Println()
+	freePages := file.FreePageMap.FreePages()
+	fmt.Printf("free pages: %d of %d (%s)\n", len(freePages), file.FileHdr.NPages, pageRanges(freePages))
+	if leaked := file.LeakedPages(); len(leaked) > 0 {
+		warn.Printf("leaked pages (marked used but not referenced): %d (%s)", len(leaked), pageRanges(leaked))
+	}
+	if freeRefs := file.FreeReferencedPages(); len(freeRefs) > 0 {
+		warn.Printf("free pages referenced by streams: %d (%s)", len(freeRefs), pageRanges(freeRefs))
+	}
+	fmt.Println()
+	pretty.Println(file.StreamTbl)
+	fmt.Println()
+	if prevStreamTbl, err := file.PrevStreamTable(); err == nil {
+		fmt.Println("previous stream table:", prevStreamTbl.NStreams, "streams")
+		for _, diff := range pdb.DiffStreamTables(prevStreamTbl, file.StreamTbl) {
+			fmt.Printf("   stream %d: %v (size %d -> %d)\n", diff.StreamNum, diff.Kind, diff.Old.Size, diff.New.Size)
+		}
+		fmt.Println()
+	} else if errors.Cause(err) != pdb.ErrNoPrevStreamTable {
+		return errors.WithStack(err)
+	}
+	fmt.Println("MSF version:", file.FileHdr.Version)
+	fmt.Println()
+	if diags := file.Diagnostics(); len(diags) > 0 {
+		fmt.Println("diagnostics:")
+		for _, d := range diags {
+			fmt.Println("  ", d)
+		}
+		fmt.Println()
+	}
+	for streamNum, streamInfo := range file.StreamTbl.StreamInfos {
+		switch {
+		case streamInfo.IsNil():
+			fmt.Printf("stream %d: nil\n", streamNum)
+		case streamInfo.Size == 0:
+			fmt.Printf("stream %d: empty\n", streamNum)
+		default:
+			fmt.Printf("stream %d: %d bytes\n", streamNum, streamInfo.Size)
 		}
-	*/
-	pretty.Println(file)
+	}
 	fmt.Println()
-	for streamNum, stream := range file.Streams {
-		streamID := pdb.StreamID(streamNum)
-		fmt.Printf("=== [ %v ] ===================================\n", streamID)
+	if pdbStream, err := file.PDBInfo(); err == nil && pdbStream.StreamNameMap != nil {
+		fmt.Println("named streams:")
+		for _, entry := range pdbStream.StreamNameMap.Entries {
+			fmt.Printf("   stream %d: %q\n", entry.StreamNum, entry.Name)
+		}
+		fmt.Println()
+	}
+	for _, stream := range file.Streams {
+		switch stream.(type) {
+		case nil, *pdb.RawStream:
+			// skip nil and undecoded streams.
+			continue
+		}
+		fmt.Printf("=== [ stream %d: %v ] ===================================\n", stream.StreamNum(), stream.Kind())
 		fmt.Println()
 		pretty.Println(stream)
 		fmt.Println()
 		switch stream := stream.(type) {
-		case *pdb.StreamTable:
+		case *pdb.PrevStreamTableStream:
 			// nothing to do.
 		case *pdb.PDBStream:
-			fmt.Println(streamID)
+			fmt.Println(stream.Kind())
 			fmt.Println("   Version:", stream.Hdr.Version)
 			fmt.Println("   Date:", stream.Hdr.Date)
 			fmt.Println("   Age:", stream.Hdr.Age)
 			fmt.Println("   UniqueID:", stream.Hdr.UniqueID)
+			fmt.Println("   Features:", stream.Features)
+			fmt.Println("   HasIPIStream:", stream.HasIPIStream())
+			fmt.Println("   IsMinimalDebugInfo:", stream.IsMinimalDebugInfo())
 			fmt.Println()
 		case *pdb.TPIStream:
-			fmt.Println(streamID)
+			fmt.Println(stream.Kind())
 			fmt.Println("   Version:", stream.Hdr.Version)
-			fmt.Println("   FirstTypeID:", stream.Hdr.FirstTypeID)
-			fmt.Println("   LastTypeID:", stream.Hdr.LastTypeID)
+			fmt.Println("   TypeIndexBegin:", stream.Hdr.TypeIndexBegin)
+			fmt.Println("   TypeIndexEnd:", stream.Hdr.TypeIndexEnd)
+			fmt.Println("   HashStreamNum:", stream.Hdr.HashStreamNum)
+			if stream.Hdr16 == nil {
+				fmt.Println("   HashAuxStreamNum:", stream.Hdr.HashAuxStreamNum)
+				fmt.Println("   HashKeySize:", stream.Hdr.HashKeySize)
+				fmt.Println("   NumHashBuckets:", stream.Hdr.NumHashBuckets)
+			}
+			fmt.Println()
+		case *pdb.DBIStream:
+			fmt.Println(stream.Kind())
+			fmt.Println("   Version:", stream.Hdr.Version)
+			fmt.Println("   Age:", stream.Hdr.Age)
+			fmt.Printf("   Machine: 0x%04X\n", stream.Hdr.Machine)
+			fmt.Println()
+		case *pdb.StringTable:
+			fmt.Println(stream.Kind())
+			fmt.Println("   HashVersion:", stream.Hdr.HashVersion)
+			fmt.Println("   NameCount:", stream.NameCount)
+			for offset := 0; offset < len(stream.Buf); {
+				s, err := stream.String(uint32(offset))
+				if err != nil {
+					warn.Printf("unable to read string at offset 0x%08X: %v", offset, err)
+					break
+				}
+				fmt.Printf("   0x%08X: %q\n", offset, s)
+				offset += len(s) + 1
+			}
+			fmt.Println()
+		case *pdb.LinkInfo:
+			fmt.Println(stream.Kind())
+			if stream.Hdr != nil {
+				fmt.Println("   Version:", stream.Hdr.Version)
+			}
+			fmt.Printf("   Cwd: %q\n", stream.Cwd)
+			fmt.Printf("   Command: %q\n", stream.Command)
+			fmt.Printf("   OutputFile: %q\n", stream.OutputFile)
+			fmt.Printf("   Libs: %q\n", stream.Libs)
+			fmt.Println()
+		case *pdb.SourceHeaderBlock:
+			fmt.Println(stream.Kind())
+			fmt.Println("   Version:", stream.Hdr.Version)
+			fmt.Println("   Age:", stream.Hdr.Age)
+			for _, src := range stream.Sources {
+				fmt.Printf("   %q\n", src.VirtualPath)
+				fmt.Printf("      FileName: %q\n", src.FileName)
+				fmt.Printf("      ObjName: %q\n", src.ObjName)
+				fmt.Printf("      CRC: 0x%08X\n", src.CRC)
+				fmt.Println("      Compression:", src.Compression)
+			}
+			fmt.Println()
+		case *pdb.SourceLink:
+			fmt.Println(stream.Kind())
+			for _, doc := range stream.Documents {
+				fmt.Printf("   %q -> %q\n", doc.Path, doc.URL)
+			}
+			fmt.Println()
+		case *pdb.SrcSrv:
+			fmt.Println(stream.Kind())
+			for _, srcFile := range stream.Files {
+				target, err := stream.Target(&srcFile)
+				if err != nil {
+					warn.Printf("unable to expand target of source file %q: %v", srcFile.Path(), err)
+					continue
+				}
+				cmd, err := stream.Command(&srcFile)
+				if err != nil {
+					warn.Printf("unable to expand command of source file %q: %v", srcFile.Path(), err)
+					continue
+				}
+				fmt.Printf("   %q\n", srcFile.Path())
+				fmt.Printf("      Target: %q\n", target)
+				if len(cmd) > 0 {
+					fmt.Printf("      Command: %q\n", cmd)
+				}
+			}
 			fmt.Println()
 		default:
 			warn.Printf("not yet pretty-printing stream %T", stream)
@@ -72,3 +231,87 @@ func pdbDump(pdbPath string) error {
 	}
 	return nil
 }
+
+// pageRanges returns a compact string representation of the given page
+// numbers in increasing order, as ranges of consecutive pages (e.g.
+// "3-7, 12, 20-31").
+func pageRanges(pageNums []int) string {
+	var ranges []string
+	for i := 0; i < len(pageNums); {
+		j := i
+		for j+1 < len(pageNums) && pageNums[j+1] == pageNums[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprint(pageNums[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", pageNums[i], pageNums[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// parseFile parses the given PDB file, optionally memory-mapping the file.
+func parseFile(pdbPath string, opts *pdb.ParseOptions, useMmap bool) (*pdb.File, error) {
+	if !useMmap {
+		file, err := pdb.ParseFile(pdbPath, opts)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return file, nil
+	}
+	file, err := pdb.OpenMmap(pdbPath, opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := file.ParseStreams(); err != nil {
+		file.Close()
+		return nil, errors.WithStack(err)
+	}
+	return file, nil
+}
+
+// extractSources writes the injected source files of the given PDB file to the
+// output directory, optionally memory-mapping the file.
+func extractSources(pdbPath, outputDir string, opts *pdb.ParseOptions, useMmap bool) error {
+	file, err := parseFile(pdbPath, opts, useMmap)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+	block, err := file.InjectedSources()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, src := range block.Sources {
+		if src.Compression != pdb.SourceCompressionNone {
+			warn.Printf("skipping injected source file %q; support for %v compression not yet implemented", src.VirtualPath, src.Compression)
+			continue
+		}
+		buf, err := file.InjectedSourceContents(src)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		outputPath := filepath.Join(outputDir, sourceRelPath(src.VirtualPath))
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return errors.WithStack(err)
+		}
+		dbg.Printf("creating %q", outputPath)
+		if err := ioutil.WriteFile(outputPath, buf, 0644); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// sourceRelPath returns a relative output path of the given virtual path of an
+// injected source file (e.g. c:\src\foo.natvis -> c/src/foo.natvis),
+// which is confined to the output directory.
+func sourceRelPath(virtualPath string) string {
+	p := strings.ReplaceAll(virtualPath, `\`, "/")
+	p = strings.ReplaceAll(p, ":", "")
+	// Clean as an absolute path to drop leading ".." elements.
+	p = path.Clean("/" + p)
+	return filepath.FromSlash(strings.TrimPrefix(p, "/"))
+}
@@ -0,0 +1,98 @@
+// The pdb_match tool reports whether PDB files match a PE/COFF image.
+//
+// Usage:
+//
+//	pdb_match IMAGE PDB...
+//
+// The CodeView debug information (RSDS or NB10 record) of the image is
+// compared against the signature and age of each PDB file. The exit status is
+// 1 if any PDB file does not match the image.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/mewkiz/pkg/term"
+	"github.com/mewrev/pdb"
+	"github.com/pkg/errors"
+)
+
+var (
+	// warn is a logger with the "pdb_match:" prefix which logs warning messages
+	// to standard error.
+	warn = log.New(os.Stderr, term.RedBold("pdb_match:")+" ", 0)
+)
+
+func usage() {
+	const use = `
+Report whether PDB files match a PE/COFF image.
+
+Usage:
+
+	pdb_match [OPTION]... IMAGE PDB...
+
+Flags:
+`
+	fmt.Fprint(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	imagePath := flag.Arg(0)
+	pdbPaths := flag.Args()[1:]
+	match, err := pdbMatch(imagePath, pdbPaths)
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	if !match {
+		os.Exit(1)
+	}
+}
+
+// pdbMatch reports whether the given PDB files match the PE/COFF image.
+func pdbMatch(imagePath string, pdbPaths []string) (bool, error) {
+	cv, err := pdb.ParseCodeViewInfo(imagePath)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	fmt.Printf("%s: %v", imagePath, cv.Signature)
+	switch cv.Signature {
+	case pdb.CodeViewSignatureRSDS:
+		fmt.Printf(" GUID %v", cv.GUID)
+	case pdb.CodeViewSignatureNB10:
+		fmt.Printf(" time stamp 0x%08X", cv.TimeStamp)
+	}
+	fmt.Printf(" age %d (%s)\n", cv.Age, cv.PDBPath)
+	match := true
+	for _, pdbPath := range pdbPaths {
+		file, err := pdb.OpenMmap(pdbPath, nil)
+		if err != nil {
+			warn.Printf("unable to open %q: %v", pdbPath, err)
+			match = false
+			continue
+		}
+		err = file.MatchCodeView(cv)
+		file.Close()
+		var mismatch *pdb.MismatchError
+		switch {
+		case err == nil:
+			fmt.Printf("%s: match\n", pdbPath)
+		case errors.As(err, &mismatch):
+			fmt.Printf("%s: mismatch: %v (image %s, PDB %s)\n", pdbPath, mismatch.Reason, mismatch.Image, mismatch.PDB)
+			match = false
+		default:
+			warn.Printf("unable to match %q: %v", pdbPath, err)
+			match = false
+		}
+	}
+	return match, nil
+}
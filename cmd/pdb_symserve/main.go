@@ -0,0 +1,75 @@
+// The pdb_symserve tool serves the PDB files of a directory tree over HTTP in
+// the URL layout of symbol stores.
+//
+// Usage:
+//
+//	pdb_symserve [OPTION]... DIR
+//
+// Debuggers may then locate PDB files through a symbol server path element
+// (e.g. "srv*C:\cache*http://localhost:8080/").
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/mewkiz/pkg/term"
+	"github.com/mewrev/pdb"
+)
+
+var (
+	// dbg is a logger with the "pdb_symserve:" prefix which logs debug
+	// messages to standard error.
+	dbg = log.New(os.Stderr, term.CyanBold("pdb_symserve:")+" ", 0)
+	// warn is a logger with the "pdb_symserve:" prefix which logs warning
+	// messages to standard error.
+	warn = log.New(os.Stderr, term.RedBold("pdb_symserve:")+" ", 0)
+)
+
+func usage() {
+	const use = `
+Serve the PDB files of a directory tree over HTTP in symbol store layout.
+
+Usage:
+
+	pdb_symserve [OPTION]... DIR
+
+Flags:
+`
+	fmt.Fprint(os.Stderr, use[1:])
+	flag.PrintDefaults()
+}
+
+func main() {
+	var (
+		// TCP address to listen on.
+		addr string
+		// Serve file pointers referring to the local path of PDB files.
+		filePtr bool
+	)
+	flag.StringVar(&addr, "addr", ":8080", "TCP address to listen on")
+	flag.BoolVar(&filePtr, "file-ptr", false, "serve file pointers (file.ptr) referring to the local path of PDB files")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	root := flag.Arg(0)
+	srv := &pdb.SymbolServer{
+		Root:    root,
+		FilePtr: filePtr,
+		Warn:    warn,
+	}
+	if err := srv.Reindex(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+	dbg.Printf("indexed %d PDB files of %q", srv.Len(), root)
+	dbg.Printf("listening on %q", addr)
+	if err := http.ListenAndServe(addr, srv); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
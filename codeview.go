@@ -0,0 +1,307 @@
+package pdb
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// CodeViewInfo records the CodeView debug information of a PE/COFF image,
+// which identifies the PDB file of the image.
+//
+// ref: https://github.com/dotnet/runtime/blob/main/docs/design/specs/PE-COFF.md#codeview-debug-directory-entry-type-2
+type CodeViewInfo struct {
+	// CodeView signature; RSDS (PDB 7.0) or NB10 (PDB 2.0).
+	Signature CodeViewSignature
+	// Unique ID of the PDB (RSDS); matches PDBStreamHeader.UniqueID.
+	GUID GUID
+	// Time stamp signature of the PDB (NB10); matches PDBStreamHeader.Date.
+	TimeStamp uint32
+	// Age of the PDB; matches the age of the DBI stream.
+	Age uint32
+	// Path of the PDB file, as recorded by the linker.
+	PDBPath string
+}
+
+//go:generate stringer -linecomment -type CodeViewSignature
+
+// CodeViewSignature specifies the format of a CodeView debug information
+// record.
+type CodeViewSignature uint32
+
+// CodeView signatures.
+const (
+	// PDB 7.0 record, identifying the PDB by GUID and age.
+	CodeViewSignatureRSDS CodeViewSignature = 0x53445352 // RSDS
+	// PDB 2.0 record, identifying the PDB by time stamp and age.
+	CodeViewSignatureNB10 CodeViewSignature = 0x3031424E // NB10
+)
+
+// Index of the debug directory in the data directories of the PE optional
+// header.
+//
+// ref: IMAGE_DIRECTORY_ENTRY_DEBUG
+const debugDirIndex = 6
+
+// Debug directory entry type of CodeView debug information.
+//
+// ref: IMAGE_DEBUG_TYPE_CODEVIEW
+const debugTypeCodeView = 2
+
+// ParseCodeViewInfo parses the CodeView debug information of the given PE/COFF
+// image, reading from imagePath.
+func ParseCodeViewInfo(imagePath string) (*CodeViewInfo, error) {
+	f, err := os.Open(imagePath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	cv, err := ReadCodeViewInfo(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read CodeView debug information of %q", imagePath)
+	}
+	return cv, nil
+}
+
+// ReadCodeViewInfo reads the CodeView debug information of a PE/COFF image from
+// r, as located by the debug directory of the image.
+func ReadCodeViewInfo(r io.ReaderAt) (*CodeViewInfo, error) {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Locate debug directory.
+	var (
+		dataDirs  []pe.DataDirectory
+		ndataDirs uint32
+	)
+	switch opt := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dataDirs, ndataDirs = opt.DataDirectory[:], opt.NumberOfRvaAndSizes
+	case *pe.OptionalHeader64:
+		dataDirs, ndataDirs = opt.DataDirectory[:], opt.NumberOfRvaAndSizes
+	default:
+		return nil, errors.New("missing PE optional header")
+	}
+	if ndataDirs <= debugDirIndex {
+		return nil, errors.New("missing debug directory")
+	}
+	debugDir := dataDirs[debugDirIndex]
+	if debugDir.VirtualAddress == 0 || debugDir.Size == 0 {
+		return nil, errors.New("missing debug directory")
+	}
+	data, err := readRVA(f, debugDir.VirtualAddress, debugDir.Size)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Locate CodeView entry of debug directory.
+	br := bytes.NewReader(data)
+	for br.Len() > 0 {
+		var entry debugDirectoryEntry
+		if err := binary.Read(br, binary.LittleEndian, &entry); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if entry.Type != debugTypeCodeView {
+			continue
+		}
+		record, err := readFullAt(r, int64(entry.PointerToRawData), int64(entry.SizeOfData))
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read CodeView record")
+		}
+		cv, err := parseCodeViewInfo(record)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return cv, nil
+	}
+	return nil, errors.New("missing CodeView entry in debug directory")
+}
+
+// debugDirectoryEntry is an entry of the debug directory of a PE/COFF image.
+//
+// ref: IMAGE_DEBUG_DIRECTORY
+type debugDirectoryEntry struct {
+	Characteristics  uint32
+	TimeDateStamp    uint32
+	MajorVersion     uint16
+	MinorVersion     uint16
+	Type             uint32
+	SizeOfData       uint32
+	AddressOfRawData uint32
+	PointerToRawData uint32
+}
+
+// readRVA reads size bytes of the given PE/COFF image starting at the given
+// relative virtual address.
+func readRVA(f *pe.File, rva, size uint32) ([]byte, error) {
+	for _, sect := range f.Sections {
+		start := sect.VirtualAddress
+		end := start + sect.Size
+		if rva < start || uint64(rva)+uint64(size) > uint64(end) {
+			continue
+		}
+		buf, err := readFullAt(sect, int64(rva-start), int64(size))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return buf, nil
+	}
+	return nil, errors.Errorf("unable to locate section containing RVA range [0x%X, 0x%X)", rva, uint64(rva)+uint64(size))
+}
+
+// readFullAt reads n bytes of r starting at the given offset. As n is read from
+// untrusted headers of the image, the returned buffer is grown as data is read,
+// so that memory is bounded by the size of the image rather than by n. An
+// error is returned if fewer than n bytes could be read.
+func readFullAt(r io.ReaderAt, off, n int64) ([]byte, error) {
+	buf, err := ioutil.ReadAll(io.NewSectionReader(r, off, n))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if int64(len(buf)) < n {
+		return nil, errors.Errorf("unable to read %d bytes at offset 0x%X; data truncated to %d bytes", n, off, len(buf))
+	}
+	return buf, nil
+}
+
+// parseCodeViewInfo parses the given CodeView debug information record.
+func parseCodeViewInfo(record []byte) (*CodeViewInfo, error) {
+	r := bytes.NewReader(record)
+	// Signature.
+	cv := &CodeViewInfo{}
+	if err := binary.Read(r, binary.LittleEndian, &cv.Signature); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch cv.Signature {
+	case CodeViewSignatureRSDS:
+		// GUID.
+		if err := binary.Read(r, binary.LittleEndian, &cv.GUID); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case CodeViewSignatureNB10:
+		// Offset; always zero.
+		var offset uint32
+		if err := binary.Read(r, binary.LittleEndian, &offset); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// TimeStamp.
+		if err := binary.Read(r, binary.LittleEndian, &cv.TimeStamp); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	default:
+		return nil, errors.Errorf("unsupported CodeView signature 0x%08X", uint32(cv.Signature))
+	}
+	// Age.
+	if err := binary.Read(r, binary.LittleEndian, &cv.Age); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// PDBPath.
+	rest := record[len(record)-r.Len():]
+	if end := bytes.IndexByte(rest, 0); end != -1 {
+		rest = rest[:end]
+	}
+	cv.PDBPath = string(rest)
+	return cv, nil
+}
+
+//go:generate stringer -linecomment -type MismatchReason
+
+// MismatchReason specifies why a PDB file does not match a PE/COFF image.
+type MismatchReason uint8
+
+// Mismatch reasons.
+const (
+	// The CodeView record and PDB file use different signature formats (e.g.
+	// an RSDS record and a PDB file without GUID).
+	MismatchFormat MismatchReason = iota + 1 // signature format mismatch
+	// The GUID of the RSDS record differs from the unique ID of the PDB.
+	MismatchGUID // GUID mismatch
+	// The time stamp of the NB10 record differs from the PDB signature.
+	MismatchTimeStamp // time stamp mismatch
+	// The age of the PDB stream is older than the age of the image (or differs
+	// from the age of the image, if the PDB file has no DBI stream).
+	MismatchAge // age mismatch
+	// The age of the DBI stream differs from the age of the image.
+	MismatchDBIAge // DBI age mismatch
+)
+
+// MismatchError records why a PDB file does not match a PE/COFF image.
+type MismatchError struct {
+	// Reason of the mismatch.
+	Reason MismatchReason
+	// Value recorded by the image.
+	Image string
+	// Value recorded by the PDB file.
+	PDB string
+}
+
+// Error returns the error message of the mismatch error.
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("pdb: PDB file does not match image: %v (image %s, PDB %s)", e.Reason, e.Image, e.PDB)
+}
+
+// MatchCodeView reports whether the PDB file matches the PE/COFF image with
+// the given CodeView debug information. A nil error is returned if the PDB
+// file matches, and a *MismatchError otherwise; other errors are returned if
+// the PDB stream or DBI stream fail to decode.
+//
+// The signature (GUID or time stamp) of the image must match the PDB stream,
+// and the age of the image must match the age of the DBI stream. As the age of
+// the PDB stream is incremented on every write of the PDB file, it may exceed
+// the age of the image, but may not be older. If the PDB file has no DBI
+// stream, the age of the PDB stream must match the age of the image.
+func (file *File) MatchCodeView(cv *CodeViewInfo) error {
+	pdbStream, err := file.PDBInfo()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	hdr := pdbStream.Hdr
+	hasGUID := hdr.Version >= PDBVersionVC70Deprecated
+	switch cv.Signature {
+	case CodeViewSignatureRSDS:
+		if !hasGUID {
+			return &MismatchError{Reason: MismatchFormat, Image: cv.Signature.String(), PDB: hdr.Version.String()}
+		}
+		if cv.GUID != hdr.UniqueID {
+			return &MismatchError{Reason: MismatchGUID, Image: cv.GUID.String(), PDB: hdr.UniqueID.String()}
+		}
+	case CodeViewSignatureNB10:
+		if hasGUID {
+			return &MismatchError{Reason: MismatchFormat, Image: cv.Signature.String(), PDB: hdr.Version.String()}
+		}
+		if sig := uint32(hdr.Date.Unix()); cv.TimeStamp != sig {
+			return &MismatchError{Reason: MismatchTimeStamp, Image: fmt.Sprintf("0x%08X", cv.TimeStamp), PDB: fmt.Sprintf("0x%08X", sig)}
+		}
+	default:
+		return errors.Errorf("unsupported CodeView signature 0x%08X", uint32(cv.Signature))
+	}
+	if hdr.Age < cv.Age {
+		return &MismatchError{Reason: MismatchAge, Image: fmt.Sprint(cv.Age), PDB: fmt.Sprint(hdr.Age)}
+	}
+	// Compare against the age of the DBI stream, if present and decoded (DBI
+	// streams with old-style headers are kept as raw streams).
+	var dbiStream *DBIStream
+	if int(StreamIDDBIStream) < len(file.StreamTbl.StreamInfos) && !file.StreamTbl.StreamInfos[StreamIDDBIStream].IsNil() {
+		stream, err := file.fixedStream(StreamIDDBIStream)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		dbiStream, _ = stream.(*DBIStream)
+	}
+	if dbiStream == nil {
+		if hdr.Age != cv.Age {
+			return &MismatchError{Reason: MismatchAge, Image: fmt.Sprint(cv.Age), PDB: fmt.Sprint(hdr.Age)}
+		}
+		return nil
+	}
+	if dbiStream.Hdr.Age != cv.Age {
+		return &MismatchError{Reason: MismatchDBIAge, Image: fmt.Sprint(cv.Age), PDB: fmt.Sprint(dbiStream.Hdr.Age)}
+	}
+	return nil
+}
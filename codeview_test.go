@@ -0,0 +1,183 @@
+package pdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestReadFullAt(t *testing.T) {
+	r := bytes.NewReader([]byte("0123456789"))
+	buf, err := readFullAt(r, 2, 4)
+	if err != nil {
+		t.Fatalf("unable to read; %+v", err)
+	}
+	if got, want := string(buf), "2345"; got != want {
+		t.Errorf("contents mismatch; expected %q, got %q", want, got)
+	}
+	// Sizes read from untrusted headers exceeding the data are reported as
+	// errors, without allocating the requested size up-front.
+	if _, err := readFullAt(r, 2, 1<<40); err == nil {
+		t.Errorf("expected error for size exceeding data, got nil")
+	}
+	if _, err := readFullAt(r, 1<<32, 4); err == nil {
+		t.Errorf("expected error for offset past end of data, got nil")
+	}
+}
+
+func TestParseCodeViewInfo(t *testing.T) {
+	rsds := []byte("RSDS" +
+		"\x40\xFC\x29\x6B\x47\xCA\x67\x10\xB3\x1D\x00\xDD\x01\x06\x62\xDA" + // GUID
+		"\x01\x00\x00\x00" + // Age
+		"C:\\build\\my app#1.pdb\x00" + // PDBPath
+		"\xFF\xFF") // padding
+	nb10 := []byte("NB10" +
+		"\x00\x00\x00\x00" + // Offset
+		"\x78\x56\x34\x12" + // TimeStamp
+		"\x03\x00\x00\x00" + // Age
+		"app.pdb\x00")
+	golden := []struct {
+		name    string
+		record  []byte
+		want    *CodeViewInfo
+		wantErr bool
+	}{
+		{name: "RSDS", record: rsds, want: testCodeView},
+		{
+			name:   "NB10",
+			record: nb10,
+			want: &CodeViewInfo{
+				Signature: CodeViewSignatureNB10,
+				TimeStamp: 0x12345678,
+				Age:       3,
+				PDBPath:   "app.pdb",
+			},
+		},
+		// PDB path missing NUL terminator; extends to the end of the record.
+		{
+			name:   "RSDS without NUL terminator",
+			record: rsds[:len(rsds)-3],
+			want:   testCodeView,
+		},
+		{
+			name:   "RSDS without PDB path",
+			record: rsds[:24],
+			want: &CodeViewInfo{
+				Signature: CodeViewSignatureRSDS,
+				GUID:      testCodeView.GUID,
+				Age:       1,
+			},
+		},
+		// Truncated records.
+		{name: "truncated signature", record: rsds[:3], wantErr: true},
+		{name: "truncated GUID", record: rsds[:19], wantErr: true},
+		{name: "truncated RSDS age", record: rsds[:23], wantErr: true},
+		{name: "truncated NB10 time stamp", record: nb10[:11], wantErr: true},
+		{name: "truncated NB10 age", record: nb10[:15], wantErr: true},
+		{name: "unknown signature", record: []byte("NB09\x00\x00\x00\x00"), wantErr: true},
+	}
+	for _, g := range golden {
+		got, err := parseCodeViewInfo(g.record)
+		if g.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", g.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unable to parse CodeView record; %+v", g.name, err)
+			continue
+		}
+		if *got != *g.want {
+			t.Errorf("%s: CodeView info mismatch; expected %+v, got %+v", g.name, g.want, got)
+		}
+	}
+}
+
+// newTestDBIStream returns the contents of a DBI stream with a new-style
+// header of the given age, and empty substreams.
+func newTestDBIStream(age uint32) []byte {
+	hdr := &DBIStreamHeader{
+		VersionSignature:   -1,
+		Version:            DBIVersionV70,
+		Age:                age,
+		GlobalStreamNum:    NilStreamNum,
+		PublicStreamNum:    NilStreamNum,
+		SymRecordStreamNum: NilStreamNum,
+	}
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, hdr)
+	return buf.Bytes()
+}
+
+// newTestNB10PDB returns the contents of a PDB file of version VC 5.0 (without
+// GUID), with the given date and age.
+func newTestNB10PDB(date, age uint32) []byte {
+	pdbStream := &bytes.Buffer{}
+	binary.Write(pdbStream, binary.LittleEndian, []uint32{uint32(PDBVersionVC50), date, age})
+	return newTestMSF(true, nil, pdbStream.Bytes()).image()
+}
+
+func TestMatchCodeView(t *testing.T) {
+	otherGUID := testCodeView.GUID
+	otherGUID.Data4[7]++
+	withGUID := func(guid GUID) *CodeViewInfo {
+		cv := *testCodeView
+		cv.GUID = guid
+		return &cv
+	}
+	withAge := func(age uint32) *CodeViewInfo {
+		cv := *testCodeView
+		cv.Age = age
+		return &cv
+	}
+	nb10 := &CodeViewInfo{Signature: CodeViewSignatureNB10, TimeStamp: 0x12345678, Age: 2}
+	golden := []struct {
+		name string
+		// PDB file contents.
+		pdb []byte
+		// CodeView debug information of image.
+		cv   *CodeViewInfo
+		want MismatchReason // 0 if match
+	}{
+		// RSDS.
+		{name: "RSDS match", pdb: newTestPDB(testCodeView), cv: testCodeView},
+		{name: "GUID mismatch", pdb: newTestPDB(testCodeView), cv: withGUID(otherGUID), want: MismatchGUID},
+		// PDB stream age may exceed the image age if the DBI stream age matches.
+		{name: "RSDS match with DBI stream", pdb: newTestPDB(withAge(3), nil, newTestDBIStream(1)), cv: testCodeView},
+		{name: "PDB age older than image", pdb: newTestPDB(testCodeView, nil, newTestDBIStream(1)), cv: withAge(2), want: MismatchAge},
+		{name: "PDB age differs without DBI stream", pdb: newTestPDB(withAge(3)), cv: testCodeView, want: MismatchAge},
+		{name: "DBI age mismatch", pdb: newTestPDB(withAge(3), nil, newTestDBIStream(2)), cv: testCodeView, want: MismatchDBIAge},
+		// NB10.
+		{name: "NB10 match", pdb: newTestNB10PDB(0x12345678, 2), cv: nb10},
+		{name: "time stamp mismatch", pdb: newTestNB10PDB(0x12345679, 2), cv: nb10, want: MismatchTimeStamp},
+		{name: "NB10 age mismatch", pdb: newTestNB10PDB(0x12345678, 1), cv: nb10, want: MismatchAge},
+		// Signature formats.
+		{name: "RSDS record and PDB without GUID", pdb: newTestNB10PDB(0x12345678, 1), cv: testCodeView, want: MismatchFormat},
+		{name: "NB10 record and PDB with GUID", pdb: newTestPDB(testCodeView), cv: nb10, want: MismatchFormat},
+	}
+	for _, g := range golden {
+		file, err := Open(bytes.NewReader(g.pdb), int64(len(g.pdb)), nil)
+		if err != nil {
+			t.Errorf("%s: unable to open PDB file; %+v", g.name, err)
+			continue
+		}
+		err = file.MatchCodeView(g.cv)
+		if g.want == 0 {
+			if err != nil {
+				t.Errorf("%s: expected match, got %+v", g.name, err)
+			}
+			continue
+		}
+		mismatch, ok := errors.Cause(err).(*MismatchError)
+		if !ok {
+			t.Errorf("%s: expected *MismatchError, got %T: %v", g.name, errors.Cause(err), err)
+			continue
+		}
+		if mismatch.Reason != g.want {
+			t.Errorf("%s: mismatch reason mismatch; expected %v, got %v", g.name, g.want, mismatch.Reason)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package pdb
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// DBIStream records debug information about the compilation of the program,
+// such as the modules (compilands) and section contributions.
+//
+// ref: https://llvm.org/docs/PDB/DbiStream.html
+type DBIStream struct {
+	streamMeta
+	// DBI stream header.
+	Hdr *DBIStreamHeader
+}
+
+// parseDBIStream parses the given DBI stream, reading from r.
+func (file *File) parseDBIStream(r io.Reader) (*DBIStream, error) {
+	// Parse DBI stream header.
+	dbiStream := &DBIStream{}
+	hdr, err := file.parseDBIStreamHeader(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dbiStream.Hdr = hdr
+	// TODO: parse DBI substreams.
+	return dbiStream, nil
+}
+
+// DBIStreamHeader is a header of the DBI stream.
+//
+// ref: NewDBIHdr in PDB/dbi/dbi.h
+type DBIStreamHeader struct {
+	// Version signature; always -1 for DBI streams with a new-style header.
+	VersionSignature int32
+	// DBI version.
+	Version DBIVersion
+	// Number of times the PDB file has been written to; matches the age of
+	// the PDB stream and the age recorded in the debug directory of the
+	// executable.
+	Age uint32
+	// Stream number of the global symbol hash stream.
+	GlobalStreamNum StreamNumber
+	// Build number of the toolchain which produced the PDB; major version in
+	// bits 8-14, minor version in bits 0-7, and new version format flag in bit
+	// 15.
+	BuildNumber uint16
+	// Stream number of the public symbol hash stream.
+	PublicStreamNum StreamNumber
+	// Version of mspdbXXXX.dll which produced the PDB.
+	PDBDLLVersion uint16
+	// Stream number of the symbol records stream.
+	SymRecordStreamNum StreamNumber
+	// Rebuild number of mspdbXXXX.dll which produced the PDB.
+	PDBDLLRebuild uint16
+	// Size in bytes of the module info substream.
+	ModInfoSize int32
+	// Size in bytes of the section contribution substream.
+	SectionContributionSize int32
+	// Size in bytes of the section map substream.
+	SectionMapSize int32
+	// Size in bytes of the source info substream.
+	SourceInfoSize int32
+	// Size in bytes of the type server map substream.
+	TypeServerMapSize int32
+	// Index of the MFC type server in the type server map substream.
+	MFCTypeServerIndex uint32
+	// Size in bytes of the optional debug header substream.
+	OptionalDbgHeaderSize int32
+	// Size in bytes of the EC substream.
+	ECSubstreamSize int32
+	// Flags (incrementally linked, private symbols stripped, conflicting
+	// types).
+	Flags uint16
+	// Machine type (as specified by the PE/COFF file header).
+	Machine uint16
+	// Padding.
+	Padding uint32
+}
+
+//go:generate stringer -linecomment -type DBIVersion
+
+// DBIVersion specifies the version of the DBI stream format. In practise,
+// V70 is almost always used.
+type DBIVersion uint32
+
+// DBI versions.
+//
+// ref: DBIImpv in PDB/dbi/dbi.h
+const (
+	DBIVersionVC41 DBIVersion = 930803   // VC 4.1 (1993-08-03)
+	DBIVersionV50  DBIVersion = 19960307 // V 5.0 (1996-03-07)
+	DBIVersionV60  DBIVersion = 19970606 // V 6.0 (1997-06-06)
+	DBIVersionV70  DBIVersion = 19990903 // V 7.0 (1999-09-03)
+	DBIVersionV110 DBIVersion = 20091201 // V 11.0 (2009-12-01)
+)
+
+// parseDBIStreamHeader parses the given DBI stream header.
+func (file *File) parseDBIStreamHeader(r io.Reader) (*DBIStreamHeader, error) {
+	hdr := &DBIStreamHeader{}
+	if err := binary.Read(r, binary.LittleEndian, hdr); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if hdr.VersionSignature != -1 {
+		return nil, unsupportedf("support for DBI stream header with version signature %d not yet implemented; expected -1 (new-style header)", hdr.VersionSignature)
+	}
+	return hdr, nil
+}
@@ -0,0 +1,41 @@
+// Code generated by "stringer -linecomment -type DBIVersion"; DO NOT EDIT.
+
+package pdb
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[DBIVersionVC41-930803]
+	_ = x[DBIVersionV50-19960307]
+	_ = x[DBIVersionV60-19970606]
+	_ = x[DBIVersionV70-19990903]
+	_ = x[DBIVersionV110-20091201]
+}
+
+const (
+	_DBIVersion_name_0 = "VC 4.1 (1993-08-03)"
+	_DBIVersion_name_1 = "V 5.0 (1996-03-07)"
+	_DBIVersion_name_2 = "V 6.0 (1997-06-06)"
+	_DBIVersion_name_3 = "V 7.0 (1999-09-03)"
+	_DBIVersion_name_4 = "V 11.0 (2009-12-01)"
+)
+
+func (i DBIVersion) String() string {
+	switch {
+	case i == 930803:
+		return _DBIVersion_name_0
+	case i == 19960307:
+		return _DBIVersion_name_1
+	case i == 19970606:
+		return _DBIVersion_name_2
+	case i == 19990903:
+		return _DBIVersion_name_3
+	case i == 20091201:
+		return _DBIVersion_name_4
+	default:
+		return "DBIVersion(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+}
@@ -0,0 +1,93 @@
+package pdb
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Diagnostic records a problem encountered while parsing a PDB file in
+// recovery mode (see ParseOptions.Recover).
+type Diagnostic struct {
+	// Severity of the problem.
+	Severity Severity
+	// Stream number of the stream in which the problem was encountered; or -1
+	// if not located within a stream.
+	StreamNum int
+	// Offset in bytes within the stream; or -1 if unknown.
+	Offset int64
+	// Diagnostic message.
+	Msg string
+	// Underlying error; or nil if not caused by an error.
+	Err error
+}
+
+// String returns the string representation of the diagnostic.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v: %s", d.Severity, d.Msg)
+}
+
+//go:generate stringer -linecomment -type Severity
+
+// Severity specifies the severity of a diagnostic.
+type Severity uint8
+
+// Diagnostic severities.
+const (
+	// Informational message (e.g. unsupported stream skipped).
+	SeverityInfo Severity = iota + 1 // info
+	// Recoverable problem; the affected data was decoded partially.
+	SeverityWarning // warning
+	// Unrecoverable problem; the affected data was kept undecoded (e.g. as raw
+	// bytes) or omitted.
+	SeverityError // error
+)
+
+// addDiagnostic records a diagnostic of the given severity caused by err. The
+// location of the diagnostic is taken from the *FormatError wrapped by err, if
+// any.
+func (file *File) addDiagnostic(severity Severity, err error) {
+	d := Diagnostic{
+		Severity:  severity,
+		StreamNum: -1,
+		Offset:    -1,
+		Msg:       err.Error(),
+		Err:       err,
+	}
+	var fe *FormatError
+	if errors.As(err, &fe) {
+		d.StreamNum = fe.StreamNum
+		d.Offset = fe.Offset
+	}
+	file.appendDiagnostic(d)
+}
+
+// addStreamDiagnostic records a diagnostic of the given severity and message,
+// located at the given offset within the stream of the given stream number.
+func (file *File) addStreamDiagnostic(severity Severity, streamNum int, off int64, format string, args ...interface{}) {
+	d := Diagnostic{
+		Severity:  severity,
+		StreamNum: streamNum,
+		Offset:    off,
+		Msg:       fmt.Sprintf(format, args...),
+	}
+	file.appendDiagnostic(d)
+}
+
+// Diagnostics returns the problems encountered while parsing in recovery mode
+// (see ParseOptions.Recover). Diagnostics is safe to call concurrently with
+// streams decoded on demand, which may record further diagnostics.
+func (file *File) Diagnostics() []Diagnostic {
+	file.mu.Lock()
+	defer file.mu.Unlock()
+	return append([]Diagnostic(nil), file.diags...)
+}
+
+// appendDiagnostic logs and records the given diagnostic. It is safe to call
+// from concurrently decoded streams.
+func (file *File) appendDiagnostic(d Diagnostic) {
+	file.warn.Println(d)
+	file.mu.Lock()
+	file.diags = append(file.diags, d)
+	file.mu.Unlock()
+}
@@ -0,0 +1,138 @@
+package pdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FormatError records a malformed PDB file, and where in the file the
+// malformed data was encountered.
+//
+// Parse errors returned by this package wrap a *FormatError, which may be
+// retrieved using errors.As.
+type FormatError struct {
+	// Stream number of the stream containing the malformed data; or -1 if not
+	// located within a stream (e.g. MSF header or stream table).
+	StreamNum int
+	// Name of the stream or structure containing the malformed data (e.g. "TPI
+	// stream", "MSF header").
+	StreamName string
+	// Offset in bytes within the stream; or -1 if unknown.
+	Offset int64
+	// Offset in bytes within the PDB file; or -1 if unknown.
+	FileOffset int64
+	// Index of the record within the stream; or -1 if not located within a
+	// record.
+	RecordIndex int
+	// Kind of the record; valid if RecordIndex != -1 (zero if unknown).
+	RecordKind TypeRecordKind
+	// Underlying error.
+	Err error
+}
+
+// newFormatError returns a new format error wrapping err, located in the
+// structure of the given name at the given file offset (or -1 if unknown).
+func newFormatError(name string, fileOff int64, err error) *FormatError {
+	return &FormatError{
+		StreamNum:   -1,
+		StreamName:  name,
+		Offset:      -1,
+		FileOffset:  fileOff,
+		RecordIndex: -1,
+		Err:         err,
+	}
+}
+
+// newRecordError returns a new format error wrapping err, located in the
+// record with the given index and kind, starting at the given offset within the
+// stream. The stream location is later recorded by StreamReader.formatError.
+func newRecordError(index int, kind TypeRecordKind, off int64, err error) *FormatError {
+	return &FormatError{
+		StreamNum:   -1,
+		Offset:      off,
+		FileOffset:  -1,
+		RecordIndex: index,
+		RecordKind:  kind,
+		Err:         err,
+	}
+}
+
+// Error returns the error message of the format error.
+func (e *FormatError) Error() string {
+	buf := &strings.Builder{}
+	buf.WriteString("pdb: ")
+	switch {
+	case e.StreamNum != -1:
+		fmt.Fprintf(buf, "stream %d (%s)", e.StreamNum, e.StreamName)
+	case len(e.StreamName) > 0:
+		buf.WriteString(e.StreamName)
+	default:
+		buf.WriteString("malformed file")
+	}
+	if e.Offset != -1 {
+		fmt.Fprintf(buf, ": offset 0x%X", e.Offset)
+		if e.FileOffset != -1 {
+			fmt.Fprintf(buf, " (file offset 0x%X)", e.FileOffset)
+		}
+	} else if e.FileOffset != -1 {
+		fmt.Fprintf(buf, ": file offset 0x%X", e.FileOffset)
+	}
+	if e.RecordIndex != -1 {
+		fmt.Fprintf(buf, ": record %d (kind 0x%04X)", e.RecordIndex, uint16(e.RecordKind))
+	}
+	fmt.Fprintf(buf, ": %v", e.Err)
+	return buf.String()
+}
+
+// Unwrap returns the underlying error of the format error.
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// unsupportedError records a stream whose format is not yet supported by the
+// parser (e.g. a DBI stream with an old-style header). Such streams are kept as
+// raw streams, unless in strict mode.
+type unsupportedError struct {
+	// Error message.
+	msg string
+}
+
+// unsupportedf returns a new error recording a stream format which is not yet
+// supported, with the given formatted message.
+func unsupportedf(format string, args ...interface{}) error {
+	return errors.WithStack(&unsupportedError{msg: fmt.Sprintf(format, args...)})
+}
+
+// Error returns the error message of the unsupported stream format error.
+func (e *unsupportedError) Error() string {
+	return e.msg
+}
+
+// isUnsupported reports whether err wraps an error recording a stream format
+// which is not yet supported.
+func isUnsupported(err error) bool {
+	var e *unsupportedError
+	return errors.As(err, &e)
+}
+
+// formatError returns an error recording the location of err within the
+// stream of the stream reader. If err already wraps a *FormatError, its stream
+// location is updated, and its offset is kept if known; otherwise, err is
+// wrapped in a new *FormatError located at the current offset of the stream
+// reader.
+func (sr *StreamReader) formatError(err error) error {
+	var fe *FormatError
+	if !errors.As(err, &fe) {
+		fe = newFormatError("", -1, err)
+		err = fe
+	}
+	fe.StreamNum = sr.streamNum
+	fe.StreamName = sr.name
+	if fe.Offset == -1 {
+		fe.Offset = sr.off
+	}
+	fe.FileOffset = sr.FileOffset(fe.Offset)
+	return err
+}
@@ -0,0 +1,180 @@
+package pdb
+
+import "github.com/pkg/errors"
+
+// FreePageMap specifies what pages are used/unused.
+//
+// In MSF 7.00, the free page map is stored in one page per interval of
+// msfHdr.PageSize pages; the first copy in pages 1, 1+PageSize,
+// 1+2*PageSize, ..., and the second copy in pages 2, 2+PageSize,
+// 2+2*PageSize, .... The bits of the free page map are stored contiguously
+// across the pages of its intervals. In MSF 2.00, each copy of the free page
+// map is stored in a single page (page 1 or 2).
+//
+// ref: https://llvm.org/docs/PDB/MsfFile.html#the-free-block-map
+// ref: FPM
+type FreePageMap struct {
+	// Page number of the first page of the free page map (either 1 or 2).
+	PageNum uint32
+	// Number of pages covered by the free page map; less than msfHdr.NPages if
+	// the page of an MSF 2.00 free page map is too small to cover every page.
+	NPages uint32
+	// Each bit specifies whether the corresponding page is used or unused.
+	//
+	//    0 = used
+	//    1 = unused
+	PageBits []byte // length: math.Ceil(msfHdr.NPages / 8)
+}
+
+// IsFree reports whether the given page number is unused. Page numbers not
+// covered by the free page map are reported as used.
+func (fpm *FreePageMap) IsFree(pageNum int) bool {
+	if pageNum < 0 || pageNum >= int(fpm.NPages) {
+		return false
+	}
+	i := pageNum / 8
+	j := pageNum % 8
+	mask := uint8(1) << j
+	return fpm.PageBits[i]&mask != 0
+}
+
+// UsedPages returns the page numbers of used pages, in increasing order.
+func (fpm *FreePageMap) UsedPages() []int {
+	var pageNums []int
+	for pageNum := 0; pageNum < int(fpm.NPages); pageNum++ {
+		if !fpm.IsFree(pageNum) {
+			pageNums = append(pageNums, pageNum)
+		}
+	}
+	return pageNums
+}
+
+// FreePages returns the page numbers of unused pages, in increasing order.
+func (fpm *FreePageMap) FreePages() []int {
+	var pageNums []int
+	for pageNum := 0; pageNum < int(fpm.NPages); pageNum++ {
+		if fpm.IsFree(pageNum) {
+			pageNums = append(pageNums, pageNum)
+		}
+	}
+	return pageNums
+}
+
+// altFreePageMapPageNum returns the page number of the alternate free page
+// map, given the page number of the active free page map.
+func altFreePageMapPageNum(fpmPageNum uint32) uint32 {
+	if fpmPageNum == 1 {
+		return 2
+	}
+	return 1
+}
+
+// readFreePageMap reads the free page map starting at the given page number,
+// concatenating the pages of each interval together.
+//
+// ref: llvm::msf::getFpmStreamLayout
+func (file *File) readFreePageMap(fpmPageNum uint32) (*FreePageMap, error) {
+	fpm := &FreePageMap{
+		PageNum: fpmPageNum,
+		NPages:  file.FileHdr.NPages,
+	}
+	npages := int(file.FileHdr.NPages)
+	size := (npages + 7) / 8 // size in bytes of free page map.
+	for _, pageNum := range file.freePageMapPageNums(fpmPageNum) {
+		if len(fpm.PageBits) >= size {
+			break
+		}
+		pageData, err := file.readPage(pageNum)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		fpm.PageBits = append(fpm.PageBits, pageData...)
+	}
+	if len(fpm.PageBits) < size && file.FileHdr.Version != MSFVersion700 {
+		fpm.NPages = uint32(len(fpm.PageBits) * 8)
+		size = len(fpm.PageBits)
+	}
+	if len(fpm.PageBits) < size {
+		return nil, errors.Errorf("free page map too short; expected >= %d bytes, got %d bytes", size, len(fpm.PageBits))
+	}
+	fpm.PageBits = fpm.PageBits[:size]
+	return fpm, nil
+}
+
+// freePageMapPageNums returns the page numbers of every interval of the free
+// page map starting at the given page number, including intervals not needed
+// to store the bits of the free page map. The free page map of MSF 2.00 is
+// stored in a single page.
+func (file *File) freePageMapPageNums(fpmPageNum uint32) []int {
+	if file.FileHdr.Version != MSFVersion700 {
+		return []int{int(fpmPageNum)}
+	}
+	pageSize := int(file.FileHdr.PageSize)
+	npages := int(file.FileHdr.NPages)
+	var pageNums []int
+	for pageNum := int(fpmPageNum); pageNum < npages; pageNum += pageSize {
+		pageNums = append(pageNums, pageNum)
+	}
+	return pageNums
+}
+
+// referencedPages returns a bitmap of the pages referenced by the MSF; i.e.
+// the MSF header, the pages of both free page maps, the stream table and its
+// page number map, and the pages of each stream.
+func (file *File) referencedPages() []bool {
+	npages := int(file.FileHdr.NPages)
+	refs := make([]bool, npages)
+	mark := func(pageNum int) {
+		if pageNum < npages {
+			refs[pageNum] = true
+		}
+	}
+	// MSF header.
+	mark(0)
+	// Free page maps.
+	for _, fpmPageNum := range []uint32{1, 2} {
+		for _, pageNum := range file.freePageMapPageNums(fpmPageNum) {
+			mark(pageNum)
+		}
+	}
+	// Stream table.
+	if file.FileHdr.Version == MSFVersion700 {
+		mark(int(file.FileHdr.PageNumMapPageNum))
+	}
+	for _, pageNum := range file.FileHdr.PageNumMap {
+		mark(int(pageNum))
+	}
+	// Streams.
+	for _, pageNumMap := range file.StreamTbl.PageNumMaps {
+		for _, pageNum := range pageNumMap {
+			mark(int(pageNum))
+		}
+	}
+	return refs
+}
+
+// LeakedPages returns the page numbers of pages marked as used by the free page
+// map which are not referenced by the MSF (see referencedPages), in increasing
+// order.
+func (file *File) LeakedPages() []int {
+	var pageNums []int
+	for pageNum, ref := range file.referencedPages() {
+		if !ref && !file.FreePageMap.IsFree(pageNum) {
+			pageNums = append(pageNums, pageNum)
+		}
+	}
+	return pageNums
+}
+
+// FreeReferencedPages returns the page numbers of pages referenced by the MSF
+// (see referencedPages) which are marked as unused by the free page map, in
+// increasing order.
+func (file *File) FreeReferencedPages() []int {
+	var pageNums []int
+	for pageNum, ref := range file.referencedPages() {
+		if ref && file.FreePageMap.IsFree(pageNum) {
+			pageNums = append(pageNums, pageNum)
+		}
+	}
+	return pageNums
+}
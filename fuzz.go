@@ -0,0 +1,28 @@
+//go:build gofuzz
+// +build gofuzz
+
+package pdb
+
+// Fuzz is the entry point of go-fuzz (github.com/dvyukov/go-fuzz), parsing the
+// given data as a PDB file.
+//
+// Usage:
+//
+//    go-fuzz-build github.com/mewrev/pdb
+//    go-fuzz -bin pdb-fuzz.zip -workdir testdata/fuzz
+func Fuzz(data []byte) int {
+	file, err := Open(&memReader{data: data}, int64(len(data)), nil)
+	if err != nil {
+		return 0
+	}
+	file.FreePageMap.UsedPages()
+	file.LeakedPages()
+	file.FreeReferencedPages()
+	if _, err := file.PrevStreamTable(); err != nil {
+		return 0
+	}
+	if err := file.ParseStreams(); err != nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,39 @@
+//go:build go1.18
+// +build go1.18
+
+package pdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzOpen(f *testing.F) {
+	// Seed corpus of well-formed MSF images, and of images with corrupt page
+	// numbers and stream sizes.
+	for _, big := range []bool{false, true} {
+		f.Add(newTestMSF(big, testStreams()...).image())
+		m := newTestMSF(big, testStreams()...)
+		m.pageNumMaps[2][1] = 1000
+		f.Add(m.image())
+		m = newTestMSF(big, testStreams()...)
+		m.sizes[0] = -5
+		f.Add(m.image())
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range []*ParseOptions{nil, {Recover: true}} {
+			file, err := Open(bytes.NewReader(data), int64(len(data)), opts)
+			if err != nil {
+				continue
+			}
+			file.FreePageMap.UsedPages()
+			file.LeakedPages()
+			file.FreeReferencedPages()
+			file.PrevStreamTable()
+			for streamNum := range file.StreamTbl.StreamInfos {
+				file.ReadStream(StreamNumber(streamNum))
+			}
+			file.ParseStreams()
+		}
+	})
+}
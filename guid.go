@@ -0,0 +1,87 @@
+package pdb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GUID is a globally unique identifier, as stored in little-endian byte order.
+//
+// ref: https://docs.microsoft.com/en-us/windows/win32/api/guiddef/ns-guiddef-guid
+type GUID struct {
+	Data1 uint32
+	Data2 uint16
+	Data3 uint16
+	Data4 [8]byte
+}
+
+// String returns the string representation of the GUID in registry form (e.g.
+// "{6B29FC40-CA47-1067-B31D-00DD010662DA}"), as displayed by debuggers.
+func (guid GUID) String() string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}", guid.Data1, guid.Data2, guid.Data3, guid.Data4[:2], guid.Data4[2:])
+}
+
+// SymstoreString returns the string representation of the GUID as used in the
+// directory layout of symbol stores; uppercase hexadecimal digits without
+// dashes (e.g. "6B29FC40CA471067B31D00DD010662DA").
+func (guid GUID) SymstoreString() string {
+	return fmt.Sprintf("%08X%04X%04X%X", guid.Data1, guid.Data2, guid.Data3, guid.Data4[:])
+}
+
+// ParseGUID parses the given string representation of a GUID, either in
+// registry form with or without braces (e.g.
+// "{6B29FC40-CA47-1067-B31D-00DD010662DA}"), or in symbol store form (e.g.
+// "6B29FC40CA471067B31D00DD010662DA"). Hexadecimal digits are case
+// insensitive.
+func ParseGUID(s string) (GUID, error) {
+	hexDigits := s
+	if strings.HasPrefix(hexDigits, "{") && strings.HasSuffix(hexDigits, "}") {
+		hexDigits = hexDigits[1 : len(hexDigits)-1]
+	}
+	if len(hexDigits) == 36 {
+		// Registry form; dashes separating groups of 8-4-4-4-12 digits.
+		for _, i := range []int{8, 13, 18, 23} {
+			if hexDigits[i] != '-' {
+				return GUID{}, errors.Errorf("invalid GUID %q; expected dash at offset %d", s, i)
+			}
+		}
+		hexDigits = strings.Replace(hexDigits, "-", "", -1)
+	}
+	if len(hexDigits) != 32 {
+		return GUID{}, errors.Errorf("invalid GUID %q; expected 32 hexadecimal digits", s)
+	}
+	buf, err := hex.DecodeString(hexDigits)
+	if err != nil {
+		return GUID{}, errors.Wrapf(err, "invalid GUID %q", s)
+	}
+	// The string representation lists Data1, Data2 and Data3 in big-endian
+	// byte order.
+	guid := GUID{
+		Data1: binary.BigEndian.Uint32(buf[0:4]),
+		Data2: binary.BigEndian.Uint16(buf[4:6]),
+		Data3: binary.BigEndian.Uint16(buf[6:8]),
+	}
+	copy(guid.Data4[:], buf[8:])
+	return guid, nil
+}
+
+// MarshalText returns the string representation of the GUID in registry form.
+// MarshalText implements encoding.TextMarshaler.
+func (guid GUID) MarshalText() ([]byte, error) {
+	return []byte(guid.String()), nil
+}
+
+// UnmarshalText parses the given string representation of a GUID, as accepted
+// by ParseGUID. UnmarshalText implements encoding.TextUnmarshaler.
+func (guid *GUID) UnmarshalText(text []byte) error {
+	g, err := ParseGUID(string(text))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	*guid = g
+	return nil
+}
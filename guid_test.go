@@ -0,0 +1,123 @@
+package pdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseGUID(t *testing.T) {
+	// Registry form lists Data1, Data2 and Data3 in big-endian byte order;
+	// GUIDs are stored in little-endian byte order.
+	const registry = "{6B29FC40-CA47-1067-B31D-00DD010662DA}"
+	stored := []byte{0x40, 0xFC, 0x29, 0x6B, 0x47, 0xCA, 0x67, 0x10, 0xB3, 0x1D, 0x00, 0xDD, 0x01, 0x06, 0x62, 0xDA}
+	var want GUID
+	if err := binary.Read(bytes.NewReader(stored), binary.LittleEndian, &want); err != nil {
+		t.Fatalf("unable to read GUID; %v", err)
+	}
+	if want != testCodeView.GUID {
+		t.Fatalf("GUID mismatch; expected %v, got %v", testCodeView.GUID, want)
+	}
+	golden := []struct {
+		s    string
+		want GUID
+	}{
+		{s: registry, want: want},
+		{s: "6B29FC40-CA47-1067-B31D-00DD010662DA", want: want},
+		{s: "6B29FC40CA471067B31D00DD010662DA", want: want},
+		{s: "{6b29fc40-ca47-1067-b31d-00dd010662da}", want: want},
+		{s: "6b29fc40ca471067b31d00dd010662da", want: want},
+		{s: "{00000000-0000-0000-0000-000000000000}", want: GUID{}},
+	}
+	for _, g := range golden {
+		got, err := ParseGUID(g.s)
+		if err != nil {
+			t.Errorf("%q: unable to parse GUID; %+v", g.s, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%q: GUID mismatch; expected %v, got %v", g.s, g.want, got)
+		}
+	}
+	// String representations.
+	if got := want.String(); got != registry {
+		t.Errorf("registry form mismatch; expected %q, got %q", registry, got)
+	}
+	if got, wantSymstore := want.SymstoreString(), "6B29FC40CA471067B31D00DD010662DA"; got != wantSymstore {
+		t.Errorf("symbol store form mismatch; expected %q, got %q", wantSymstore, got)
+	}
+	// Round-trip through each form.
+	for _, s := range []string{want.String(), want.String()[1 : len(registry)-1], want.SymstoreString()} {
+		got, err := ParseGUID(s)
+		if err != nil {
+			t.Errorf("%q: unable to parse GUID; %+v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: round-trip mismatch; expected %v, got %v", s, want, got)
+		}
+	}
+}
+
+func TestParseGUIDInvalid(t *testing.T) {
+	golden := []string{
+		// Wrong lengths.
+		"",
+		"{}",
+		"6B29FC40CA471067B31D00DD010662D",
+		"6B29FC40CA471067B31D00DD010662DA0",
+		"{6B29FC40-CA47-1067-B31D-00DD010662D}",
+		"{6B29FC40-CA47-1067-B31D-00DD010662DA0}",
+		// Unbalanced braces.
+		"{6B29FC40-CA47-1067-B31D-00DD010662DA",
+		"6B29FC40-CA47-1067-B31D-00DD010662DA}",
+		// Misplaced dashes.
+		"{6B29FC4-0CA47-1067-B31D-00DD010662DA}",
+		"{6B29FC40-CA471-067-B31D-00DD010662DA}",
+		"{6B29FC40-CA47-1067B-31D-00DD010662DA}",
+		"{6B29FC40-CA47-1067-B31D00-DD010662DA}",
+		"6B29FC40-CA47-1067-B31D00DD010662DA",
+		// Non-hexadecimal digits.
+		"{6B29FC40-CA47-1067-B31D-00DD010662DG}",
+		"6B29FC40CA471067B31D00DD010662DX",
+		"6B29FC40CA471067B31D00DD0106 2DA",
+	}
+	for _, s := range golden {
+		if guid, err := ParseGUID(s); err == nil {
+			t.Errorf("%q: expected error, got %v", s, guid)
+		}
+	}
+}
+
+func TestGUIDText(t *testing.T) {
+	// GUIDs are marshaled in registry form, e.g. as JSON strings.
+	v := struct {
+		GUID GUID `json:"guid"`
+	}{GUID: testCodeView.GUID}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unable to marshal GUID; %v", err)
+	}
+	if got, want := string(buf), `{"guid":"{6B29FC40-CA47-1067-B31D-00DD010662DA}"}`; got != want {
+		t.Errorf("JSON mismatch; expected %s, got %s", want, got)
+	}
+	v.GUID = GUID{}
+	if err := json.Unmarshal(buf, &v); err != nil {
+		t.Fatalf("unable to unmarshal GUID; %+v", err)
+	}
+	if v.GUID != testCodeView.GUID {
+		t.Errorf("GUID mismatch; expected %v, got %v", testCodeView.GUID, v.GUID)
+	}
+	// Symbol store form is accepted by UnmarshalText.
+	var guid GUID
+	if err := guid.UnmarshalText([]byte("6B29FC40CA471067B31D00DD010662DA")); err != nil {
+		t.Fatalf("unable to unmarshal GUID; %+v", err)
+	}
+	if guid != testCodeView.GUID {
+		t.Errorf("GUID mismatch; expected %v, got %v", testCodeView.GUID, guid)
+	}
+	if err := guid.UnmarshalText([]byte("invalid")); err == nil {
+		t.Errorf("expected error for invalid GUID, got nil")
+	}
+}
@@ -0,0 +1,263 @@
+package pdb
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SourceHeaderBlock lists the source files injected into the PDB file (e.g.
+// using the /INJECTSOURCE linker option, or embedded natvis files), as stored
+// in the "/src/headerblock" stream. The contents of each injected source file
+// are stored in a named stream "/src/files/<virtual path>".
+//
+// ref: https://llvm.org/docs/PDB/PdbStream.html
+// ref: InjectedSourceStream in llvm/lib/DebugInfo/PDB/Native/InjectedSourceStream.cpp
+type SourceHeaderBlock struct {
+	streamMeta
+	// Source header block header.
+	Hdr *SourceHeaderBlockHeader
+	// Injected source files, sorted by virtual path.
+	Sources []*InjectedSource
+}
+
+// SourceHeaderBlockStreamName is the name of the source header block stream,
+// as located by the named stream map.
+const SourceHeaderBlockStreamName = "/src/headerblock"
+
+// InjectedSourceStreamPrefix is the prefix of the names of streams holding the
+// contents of injected source files, followed by the virtual path of the
+// source file.
+const InjectedSourceStreamPrefix = "/src/files/"
+
+// InjectedSources returns the source header block of the PDB file, as stored in
+// the "/src/headerblock" stream, decoding it if not already present in
+// file.Streams.
+func (file *File) InjectedSources() (*SourceHeaderBlock, error) {
+	stream, err := file.StreamByName(SourceHeaderBlockStreamName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block, ok := stream.(*SourceHeaderBlock)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %q stream", SourceHeaderBlockStreamName)
+	}
+	return block, nil
+}
+
+// SourceHeaderBlockHeader is a header of the source header block stream.
+type SourceHeaderBlockHeader struct {
+	// Source header block version.
+	Version SourceHeaderBlockVersion
+	// Size in bytes of the source header block stream.
+	Size uint32
+	// Time stamp of the source header block (Windows FILETIME format).
+	FileTime uint64
+	// Age of the source header block.
+	Age uint32
+	// Padding to 64 bytes.
+	Padding [44]byte
+}
+
+//go:generate stringer -linecomment -type SourceHeaderBlockVersion
+
+// SourceHeaderBlockVersion specifies the version of a source header block.
+type SourceHeaderBlockVersion uint32
+
+// Source header block versions.
+const (
+	SourceHeaderBlockV1 SourceHeaderBlockVersion = 19980827 // V1
+)
+
+// SourceHeaderBlockEntry is an entry of the source header block, describing an
+// injected source file. Names are specified as offsets into the string table
+// ("/names" stream).
+type SourceHeaderBlockEntry struct {
+	// Size in bytes of the entry.
+	Size uint32
+	// Source header block version.
+	Version SourceHeaderBlockVersion
+	// CRC of the contents of the source file.
+	CRC uint32
+	// Size in bytes of the contents of the source file.
+	FileSize uint32
+	// File name; offset into the string table.
+	FileNameOffset uint32
+	// Object name; offset into the string table.
+	ObjNameOffset uint32
+	// Virtual path; offset into the string table.
+	VirtualPathOffset uint32
+	// Compression of the contents of the source file.
+	Compression SourceCompression
+	// Virtual file flag; set if the source file is injected.
+	IsVirtual uint8
+	// Padding.
+	Padding uint16
+	// Reserved.
+	Reserved [8]byte
+}
+
+// sourceHeaderBlockEntrySize is the size in bytes of a source header block
+// entry.
+const sourceHeaderBlockEntrySize = 40
+
+//go:generate stringer -linecomment -type SourceCompression
+
+// SourceCompression specifies the compression of an injected source file.
+type SourceCompression uint8
+
+// Source compressions.
+const (
+	SourceCompressionNone    SourceCompression = 0   // none
+	SourceCompressionRLE     SourceCompression = 1   // run-length encoded
+	SourceCompressionHuffman SourceCompression = 2   // Huffman
+	SourceCompressionLZ      SourceCompression = 3   // LZ
+	SourceCompressionDotNet  SourceCompression = 101 // .NET
+)
+
+// InjectedSource is an injected source file, with names resolved through the
+// string table.
+type InjectedSource struct {
+	// Source header block entry.
+	Entry *SourceHeaderBlockEntry
+	// File name of the source file.
+	FileName string
+	// Object name of the source file.
+	ObjName string
+	// Virtual path of the source file; locates the stream holding the contents
+	// of the source file.
+	VirtualPath string
+	// CRC of the contents of the source file.
+	CRC uint32
+	// Compression of the contents of the source file.
+	Compression SourceCompression
+}
+
+// StreamName returns the name of the stream holding the contents of the
+// injected source file.
+func (src *InjectedSource) StreamName() string {
+	return InjectedSourceStreamPrefix + src.VirtualPath
+}
+
+// InjectedSourceContents returns the contents of the given injected source
+// file.
+//
+// Only uncompressed source files (SourceCompressionNone) are supported; the RLE,
+// Huffman, LZ and .NET compression formats are not publicly documented, and an
+// error is returned for source files using them. Callers extracting several
+// source files should check InjectedSource.Compression to skip such files.
+func (file *File) InjectedSourceContents(src *InjectedSource) ([]byte, error) {
+	if src.Compression != SourceCompressionNone {
+		return nil, errors.Errorf("support for %v compression of injected source file %q not yet implemented", src.Compression, src.VirtualPath)
+	}
+	streamNum, err := file.lookupInjectedSource(src)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	buf, err := file.ReadStream(streamNum)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if uint64(len(buf)) < uint64(src.Entry.FileSize) {
+		return nil, errors.Errorf("injected source file %q too short; expected %d bytes, got %d bytes", src.VirtualPath, src.Entry.FileSize, len(buf))
+	}
+	return buf[:src.Entry.FileSize], nil
+}
+
+// lookupInjectedSource returns the stream number of the stream holding the
+// contents of the given injected source file. Tools differ in the case of
+// stream names, so the stream name is matched case-insensitively if no exact
+// match is found.
+func (file *File) lookupInjectedSource(src *InjectedSource) (StreamNumber, error) {
+	streamNameMap, err := file.streamNameMap()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	name := src.StreamName()
+	if streamNum, ok := streamNameMap.Lookup(name); ok {
+		return streamNum, nil
+	}
+	for _, entry := range streamNameMap.Entries {
+		if strings.EqualFold(entry.Name, name) {
+			return entry.StreamNum, nil
+		}
+	}
+	return 0, errors.Errorf("unable to locate stream %q in named stream map", name)
+}
+
+// parseSourceHeaderBlock parses the given source header block stream, reading
+// from r.
+func (file *File) parseSourceHeaderBlock(r *StreamReader) (*SourceHeaderBlock, error) {
+	// Parse source header block header.
+	hdr, err := file.parseSourceHeaderBlockHeader(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if hdr.Version != SourceHeaderBlockV1 {
+		return nil, errors.Errorf("support for source header block version %v not yet implemented", hdr.Version)
+	}
+	// Hash table mapping from virtual path to source header block entry.
+	hashTbl, err := parseHashTable(r, sourceHeaderBlockEntrySize)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	block := &SourceHeaderBlock{Hdr: hdr}
+	if hashTbl.size == 0 {
+		return block, nil
+	}
+	// Names are resolved through the string table, which must not be located
+	// in the source header block stream itself.
+	if streamNum, err := file.lookupStreamName(StringTableStreamName); err == nil && int(streamNum) == r.streamNum {
+		return nil, errors.Errorf("invalid %q stream; stream %d also holds the source header block", StringTableStreamName, streamNum)
+	}
+	strTbl, err := file.Names()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i := uint32(0); i < hashTbl.size; i++ {
+		// Key; offset of virtual path in string table.
+		var key uint32
+		if err := binary.Read(r, binary.LittleEndian, &key); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Value; source header block entry.
+		entry := &SourceHeaderBlockEntry{}
+		if err := binary.Read(r, binary.LittleEndian, entry); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if entry.Version != SourceHeaderBlockV1 {
+			return nil, errors.Errorf("support for source header block entry version %v not yet implemented", entry.Version)
+		}
+		src := &InjectedSource{
+			Entry:       entry,
+			CRC:         entry.CRC,
+			Compression: entry.Compression,
+		}
+		if src.FileName, err = strTbl.String(entry.FileNameOffset); err != nil {
+			return nil, errors.Wrap(err, "invalid file name of injected source file")
+		}
+		if src.ObjName, err = strTbl.String(entry.ObjNameOffset); err != nil {
+			return nil, errors.Wrap(err, "invalid object name of injected source file")
+		}
+		if src.VirtualPath, err = strTbl.String(entry.VirtualPathOffset); err != nil {
+			return nil, errors.Wrap(err, "invalid virtual path of injected source file")
+		}
+		block.Sources = append(block.Sources, src)
+	}
+	sort.SliceStable(block.Sources, func(i, j int) bool {
+		return block.Sources[i].VirtualPath < block.Sources[j].VirtualPath
+	})
+	return block, nil
+}
+
+// parseSourceHeaderBlockHeader parses the given source header block header.
+func (file *File) parseSourceHeaderBlockHeader(r io.Reader) (*SourceHeaderBlockHeader, error) {
+	hdr := &SourceHeaderBlockHeader{}
+	if err := binary.Read(r, binary.LittleEndian, hdr); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return hdr, nil
+}
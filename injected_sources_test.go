@@ -0,0 +1,134 @@
+package pdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// newTestInjectedPDB returns the contents of a PDB file holding two injected
+// source files, "c:\src\main.c" (uncompressed) and "c:\src\app.natvis" (LZ
+// compressed), with the given source header block and entry versions. The
+// stream of main.c is named in upper case.
+func newTestInjectedPDB(version, entryVersion SourceHeaderBlockVersion) []byte {
+	// String table; offsets 1 (main.c), 8 (main.obj), 17 (c:\src\main.c), 31
+	// (app.natvis) and 42 (c:\src\app.natvis).
+	strBuf := "\x00main.c\x00main.obj\x00c:\\src\\main.c\x00app.natvis\x00c:\\src\\app.natvis\x00"
+	names := &bytes.Buffer{}
+	binary.Write(names, binary.LittleEndian, []uint32{stringTableSignature, uint32(StringTableHashV1), uint32(len(strBuf))})
+	names.WriteString(strBuf)
+	binary.Write(names, binary.LittleEndian, []uint32{0, 0}) // NBuckets, NameCount
+	// Source header block.
+	block := &bytes.Buffer{}
+	w := func(v interface{}) {
+		binary.Write(block, binary.LittleEndian, v)
+	}
+	w(&SourceHeaderBlockHeader{Version: version, FileTime: 0x01D1A2B3C4D5E6F7, Age: 1})
+	w([]uint32{2, 2, 1, 0x3, 0}) // Size, Capacity, Present, Deleted
+	entries := []*SourceHeaderBlockEntry{
+		{
+			Size:              sourceHeaderBlockEntrySize,
+			Version:           entryVersion,
+			CRC:               0xDEADBEEF,
+			FileSize:          6,
+			FileNameOffset:    1,
+			ObjNameOffset:     8,
+			VirtualPathOffset: 17,
+			Compression:       SourceCompressionNone,
+			IsVirtual:         1,
+		},
+		{
+			Size:              sourceHeaderBlockEntrySize,
+			Version:           entryVersion,
+			CRC:               0x12345678,
+			FileSize:          100,
+			FileNameOffset:    31,
+			ObjNameOffset:     8,
+			VirtualPathOffset: 42,
+			Compression:       SourceCompressionLZ,
+		},
+	}
+	for _, entry := range entries {
+		w(entry.VirtualPathOffset)
+		w(entry)
+	}
+	return newTestNamedPDB(testCodeView, map[string][]byte{
+		StringTableStreamName:                            names.Bytes(),
+		SourceHeaderBlockStreamName:                      block.Bytes(),
+		InjectedSourceStreamPrefix + `C:\SRC\MAIN.C`:     []byte("int x;\ntrailing"),
+		InjectedSourceStreamPrefix + `c:\src\app.natvis`: []byte("compressed"),
+	})
+}
+
+func TestInjectedSources(t *testing.T) {
+	buf := newTestInjectedPDB(SourceHeaderBlockV1, SourceHeaderBlockV1)
+	file := openTestPDB(t, buf)
+	block, err := file.InjectedSources()
+	if err != nil {
+		t.Fatalf("unable to decode source header block; %+v", err)
+	}
+	if got, want := block.Hdr.FileTime, uint64(0x01D1A2B3C4D5E6F7); got != want {
+		t.Errorf("file time mismatch; expected 0x%016X, got 0x%016X", want, got)
+	}
+	if len(block.Sources) != 2 {
+		t.Fatalf("number of injected source files mismatch; expected 2, got %d", len(block.Sources))
+	}
+	// Entries of 40 bytes are decoded in sequence; sorted by virtual path.
+	golden := []struct {
+		fileName    string
+		objName     string
+		virtualPath string
+		crc         uint32
+		fileSize    uint32
+		compression SourceCompression
+		isVirtual   uint8
+	}{
+		{fileName: "app.natvis", objName: "main.obj", virtualPath: `c:\src\app.natvis`, crc: 0x12345678, fileSize: 100, compression: SourceCompressionLZ},
+		{fileName: "main.c", objName: "main.obj", virtualPath: `c:\src\main.c`, crc: 0xDEADBEEF, fileSize: 6, compression: SourceCompressionNone, isVirtual: 1},
+	}
+	for i, g := range golden {
+		src := block.Sources[i]
+		if src.FileName != g.fileName || src.ObjName != g.objName || src.VirtualPath != g.virtualPath {
+			t.Errorf("source %d: names mismatch; expected (%q, %q, %q), got (%q, %q, %q)", i, g.fileName, g.objName, g.virtualPath, src.FileName, src.ObjName, src.VirtualPath)
+		}
+		if src.CRC != g.crc || src.Entry.FileSize != g.fileSize || src.Compression != g.compression || src.Entry.IsVirtual != g.isVirtual {
+			t.Errorf("source %d: entry mismatch; expected CRC 0x%08X, size %d, %v, virtual %d, got CRC 0x%08X, size %d, %v, virtual %d", i, g.crc, g.fileSize, g.compression, g.isVirtual, src.CRC, src.Entry.FileSize, src.Compression, src.Entry.IsVirtual)
+		}
+	}
+	// Stream of main.c located case-insensitively, truncated to file size.
+	contents, err := file.InjectedSourceContents(block.Sources[1])
+	if err != nil {
+		t.Fatalf("unable to read contents of injected source file; %+v", err)
+	}
+	if want := "int x;"; string(contents) != want {
+		t.Errorf("contents mismatch; expected %q, got %q", want, contents)
+	}
+	// Compressed source files are not supported.
+	if _, err := file.InjectedSourceContents(block.Sources[0]); err == nil {
+		t.Errorf("expected error for compressed injected source file, got nil")
+	}
+	// Missing stream.
+	missing := *block.Sources[1]
+	missing.VirtualPath = `c:\src\missing.c`
+	if _, err := file.InjectedSourceContents(&missing); err == nil {
+		t.Errorf("expected error for missing injected source stream, got nil")
+	}
+}
+
+func TestInjectedSourcesVersion(t *testing.T) {
+	golden := []struct {
+		name         string
+		version      SourceHeaderBlockVersion
+		entryVersion SourceHeaderBlockVersion
+	}{
+		{name: "header version", version: 19980826, entryVersion: SourceHeaderBlockV1},
+		{name: "entry version", version: SourceHeaderBlockV1, entryVersion: 19980826},
+	}
+	for _, g := range golden {
+		buf := newTestInjectedPDB(g.version, g.entryVersion)
+		file := openTestPDB(t, buf)
+		if _, err := file.InjectedSources(); err == nil {
+			t.Errorf("%s: expected error for unsupported version, got nil", g.name)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package pdb
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// LinkInfo records information about the link of the program, as stored in the
+// "/LinkInfo" stream; i.e. the current working directory, command line and
+// output file of the linker.
+//
+// ref: LinkInfo in PDB/include/pdb.h
+type LinkInfo struct {
+	streamMeta
+	// Link info header; or nil if the stream is empty.
+	Hdr *LinkInfoHeader
+	// Current working directory of the linker.
+	Cwd string
+	// Command line of the linker.
+	Command string
+	// Output file of the linker, as specified by the command line.
+	OutputFile string
+	// Libraries.
+	Libs []string
+}
+
+// LinkInfoStreamName is the name of the link info stream, as located by the
+// named stream map.
+const LinkInfoStreamName = "/LinkInfo"
+
+// LinkInfo returns the link info of the PDB file, as stored in the "/LinkInfo"
+// stream, decoding it if not already present in file.Streams.
+func (file *File) LinkInfo() (*LinkInfo, error) {
+	stream, err := file.StreamByName(LinkInfoStreamName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	linkInfo, ok := stream.(*LinkInfo)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %q stream", LinkInfoStreamName)
+	}
+	return linkInfo, nil
+}
+
+// LinkInfoHeader is a header of the link info stream. Offsets are relative to
+// the start of the header.
+type LinkInfoHeader struct {
+	// Size in bytes of the link info, including header and strings.
+	Size uint32
+	// Link info version.
+	Version uint32
+	// Offset of the current working directory.
+	CwdOffset uint32
+	// Offset of the command line.
+	CommandOffset uint32
+	// Index of the output file relative to the start of the command line.
+	OutputFileIndex uint32
+	// Offset of the libraries.
+	LibsOffset uint32
+}
+
+// parseLinkInfo parses the given link info stream, reading from r.
+func (file *File) parseLinkInfo(r *StreamReader) (*LinkInfo, error) {
+	linkInfo := &LinkInfo{}
+	if r.Size() == 0 {
+		return linkInfo, nil
+	}
+	// Parse link info header.
+	hdr, err := file.parseLinkInfoHeader(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	linkInfo.Hdr = hdr
+	if int64(hdr.Size) > r.Size() {
+		return nil, errors.Errorf("invalid link info size; expected <= %d, got %d", r.Size(), hdr.Size)
+	}
+	buf := make([]byte, hdr.Size)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Cwd.
+	if linkInfo.Cwd, err = cString(buf, hdr.CwdOffset); err != nil {
+		return nil, errors.Wrap(err, "invalid current working directory")
+	}
+	// Command.
+	if linkInfo.Command, err = cString(buf, hdr.CommandOffset); err != nil {
+		return nil, errors.Wrap(err, "invalid command line")
+	}
+	// OutputFile; located by index relative to the command line, either within
+	// the command line or following it.
+	outputFileOffset := uint64(hdr.CommandOffset) + uint64(hdr.OutputFileIndex)
+	if outputFileOffset >= uint64(len(buf)) {
+		return nil, errors.Errorf("invalid output file index %d; expected < %d", hdr.OutputFileIndex, uint64(len(buf))-uint64(hdr.CommandOffset))
+	}
+	if linkInfo.OutputFile, err = cString(buf, uint32(outputFileOffset)); err != nil {
+		return nil, errors.Wrap(err, "invalid output file")
+	}
+	// Libs; NULL-terminated strings up to the end of the link info, or an
+	// empty string.
+	for off := hdr.LibsOffset; off < hdr.Size; {
+		lib, err := cString(buf, off)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid library")
+		}
+		if len(lib) == 0 {
+			break
+		}
+		linkInfo.Libs = append(linkInfo.Libs, lib)
+		off += uint32(len(lib)) + 1
+	}
+	return linkInfo, nil
+}
+
+// parseLinkInfoHeader parses the given link info header.
+func (file *File) parseLinkInfoHeader(r io.Reader) (*LinkInfoHeader, error) {
+	hdr := &LinkInfoHeader{}
+	if err := binary.Read(r, binary.LittleEndian, hdr); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return hdr, nil
+}
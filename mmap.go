@@ -0,0 +1,76 @@
+package pdb
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// errClosed is returned when reading from a PDB file which has been closed.
+var errClosed = errors.New("pdb: file already closed")
+
+// slicer is implemented by underlying readers of PDB file contents which hold
+// the entire file in memory (e.g. memory-mapped files), and may thus provide
+// direct access to its contents without copying.
+type slicer interface {
+	// slice returns n bytes of the file contents starting at the given offset.
+	// The returned slice aliases the underlying memory and must not be
+	// modified.
+	slice(off, n int64) ([]byte, error)
+}
+
+// memReader is an in-memory reader of PDB file contents.
+type memReader struct {
+	// File contents.
+	data []byte
+}
+
+// ReadAt reads len(p) bytes from the given offset of the file into p.
+func (m *memReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.Errorf("invalid negative file offset %d", off)
+	}
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// slice returns n bytes of the file contents starting at the given offset,
+// without copying.
+func (m *memReader) slice(off, n int64) ([]byte, error) {
+	if off < 0 || n < 0 || off > int64(len(m.data)) || n > int64(len(m.data))-off {
+		return nil, errors.Errorf("invalid file range [0x%X, 0x%X); expected within file of size 0x%X", off, off+n, len(m.data))
+	}
+	return m.data[off : off+n : off+n], nil
+}
+
+// closedReader is the underlying reader of a closed PDB file.
+type closedReader struct{}
+
+// ReadAt returns errClosed.
+func (closedReader) ReadAt(p []byte, off int64) (int, error) {
+	return 0, errClosed
+}
+
+// Close releases the resources associated with the PDB file, such as the
+// memory mapping of a file opened by OpenMmap. Subsequent reads from the file
+// fail. The underlying reader of a file opened by Open is not closed.
+//
+// Slices returned by zero-copy accessors (e.g. StreamReader.Bytes) and record
+// contents decoded from a memory-mapped file alias the mapping, and must not be
+// accessed after Close.
+func (file *File) Close() error {
+	file.r = closedReader{}
+	if c := file.closer; c != nil {
+		file.closer = nil
+		if err := c.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package pdb
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// OpenMmap opens the PDB file at pdbPath, mapping its contents read-only into
+// memory. As with Open, the contents of streams are only decoded on demand;
+// page and stream contents are sliced directly from the mapping, so that
+// resident memory is governed by the page cache rather than the Go heap.
+//
+// The returned file must be closed using Close, which unmaps the file. A nil
+// opts uses default options.
+func OpenMmap(pdbPath string, opts *ParseOptions) (*File, error) {
+	f, err := os.Open(pdbPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// The mapping remains valid after the file descriptor is closed.
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	size := fi.Size()
+	if size <= 0 {
+		return nil, errors.WithStack(newFormatError("MSF header", 0, errors.Errorf("empty file %q", pdbPath)))
+	}
+	if int64(int(size)) != size {
+		return nil, errors.Errorf("file %q too large to map (%d bytes)", pdbPath, size)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to map file %q", pdbPath)
+	}
+	m := &mmapReader{memReader: memReader{data: data}}
+	file, err := Open(m, size, opts)
+	if err != nil {
+		m.Close()
+		return nil, errors.WithStack(err)
+	}
+	file.closer = m
+	return file, nil
+}
+
+// mmapReader is a reader of memory-mapped PDB file contents.
+type mmapReader struct {
+	memReader
+}
+
+// Close unmaps the file contents.
+func (m *mmapReader) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	data := m.data
+	m.data = nil
+	if err := syscall.Munmap(data); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
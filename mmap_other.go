@@ -0,0 +1,35 @@
+//go:build !linux
+// +build !linux
+
+package pdb
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// OpenMmap opens the PDB file at pdbPath. Memory mapping is only supported on
+// Linux; on other platforms, the contents of the file are read on demand
+// through os.File, as with Open.
+//
+// The returned file must be closed using Close, which closes the underlying
+// file. A nil opts uses default options.
+func OpenMmap(pdbPath string, opts *ParseOptions) (*File, error) {
+	f, err := os.Open(pdbPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.WithStack(err)
+	}
+	file, err := Open(f, fi.Size(), opts)
+	if err != nil {
+		f.Close()
+		return nil, errors.WithStack(err)
+	}
+	file.closer = f
+	return file, nil
+}
@@ -0,0 +1,382 @@
+package pdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// testPageSize is the page size in bytes of test MSF images.
+const testPageSize = 512
+
+// testMSF is an in-memory MSF image, built by tests to exercise the parsing of
+// well-formed and malformed MSF headers and stream tables.
+type testMSF struct {
+	// MSF 7.00 (big MSF) if set; MSF 2.00 otherwise.
+	big bool
+	// Contents of pages; pages 0, 1 and 2 hold the MSF header and free page
+	// maps.
+	pages [][]byte
+	// Stream sizes of the stream table; -1 for nil streams.
+	sizes []int32
+	// Page number maps of the stream table.
+	pageNumMaps [][]uint32
+	// Number of pages recorded in the MSF header; or 0 to use the number of
+	// pages of the image.
+	npages uint32
+	// Size in bytes of the stream table recorded in the MSF header; or 0 to
+	// use the size of the encoded stream table.
+	streamTblSize int32
+	// Page number of the page number map of the stream table recorded in the
+	// MSF header (MSF 7.00); or 0 to use the page of the encoded map.
+	pageNumMapPageNum uint32
+}
+
+// newTestMSF returns a new MSF image holding the given streams; nil streams
+// are marked as nil in the stream table.
+func newTestMSF(big bool, streams ...[]byte) *testMSF {
+	m := &testMSF{big: big}
+	for i := 0; i < 3; i++ {
+		m.pages = append(m.pages, make([]byte, testPageSize))
+	}
+	for _, stream := range streams {
+		if stream == nil {
+			m.sizes = append(m.sizes, nilStreamSize)
+			m.pageNumMaps = append(m.pageNumMaps, nil)
+			continue
+		}
+		m.sizes = append(m.sizes, int32(len(stream)))
+		m.pageNumMaps = append(m.pageNumMaps, m.alloc(stream))
+	}
+	return m
+}
+
+// alloc stores the given data in newly allocated pages, returning their page
+// numbers.
+func (m *testMSF) alloc(data []byte) []uint32 {
+	var pageNums []uint32
+	for len(data) > 0 {
+		page := make([]byte, testPageSize)
+		n := copy(page, data)
+		data = data[n:]
+		pageNums = append(pageNums, uint32(len(m.pages)))
+		m.pages = append(m.pages, page)
+	}
+	return pageNums
+}
+
+// image returns the contents of the MSF image, encoding the stream table and
+// MSF header into newly allocated pages.
+func (m *testMSF) image() []byte {
+	// Stream table.
+	streamTbl := &bytes.Buffer{}
+	le := func(v interface{}) {
+		binary.Write(streamTbl, binary.LittleEndian, v)
+	}
+	le(uint32(len(m.sizes)))
+	for _, size := range m.sizes {
+		le(size)
+		if !m.big {
+			le(int32(0))
+		}
+	}
+	for _, pageNumMap := range m.pageNumMaps {
+		for _, pageNum := range pageNumMap {
+			if m.big {
+				le(pageNum)
+			} else {
+				le(uint16(pageNum))
+			}
+		}
+	}
+	streamTblPageNums := m.alloc(streamTbl.Bytes())
+	streamTblSize := int32(streamTbl.Len())
+	if m.streamTblSize != 0 {
+		streamTblSize = m.streamTblSize
+	}
+	// MSF header.
+	hdr := &bytes.Buffer{}
+	w := func(v interface{}) {
+		binary.Write(hdr, binary.LittleEndian, v)
+	}
+	if m.big {
+		pageNumMap := &bytes.Buffer{}
+		binary.Write(pageNumMap, binary.LittleEndian, streamTblPageNums)
+		pageNumMapPageNum := m.alloc(pageNumMap.Bytes())[0]
+		if m.pageNumMapPageNum != 0 {
+			pageNumMapPageNum = m.pageNumMapPageNum
+		}
+		npages := uint32(len(m.pages))
+		if m.npages != 0 {
+			npages = m.npages
+		}
+		hdr.WriteString(msfSignatureBig)
+		w(int32(testPageSize))
+		w(uint32(1)) // FreePageMapPageNum
+		w(npages)
+		w(StreamInfo{Size: streamTblSize})
+		w(pageNumMapPageNum)
+	} else {
+		npages := uint32(len(m.pages))
+		if m.npages != 0 {
+			npages = m.npages
+		}
+		hdr.WriteString(msfSignature)
+		w(int32(testPageSize))
+		w(uint16(1)) // FreePageMapPageNum
+		w(uint16(npages))
+		w(StreamInfo{Size: streamTblSize})
+		for _, pageNum := range streamTblPageNums {
+			w(uint16(pageNum))
+		}
+	}
+	copy(m.pages[0], hdr.Bytes())
+	var buf []byte
+	for _, page := range m.pages {
+		buf = append(buf, page...)
+	}
+	return buf
+}
+
+// testStreams returns the contents of the streams of test MSF images; a
+// single-page stream, a nil stream and a stream spanning three pages.
+func testStreams() [][]byte {
+	small := bytes.Repeat([]byte{0xAA}, 100)
+	large := make([]byte, 2*testPageSize+10)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	return [][]byte{small, nil, large}
+}
+
+// msfName returns the name of the MSF version used by test MSF images.
+func msfName(big bool) string {
+	if big {
+		return "MSF 7.00"
+	}
+	return "MSF 2.00"
+}
+
+func TestOpenValid(t *testing.T) {
+	for _, big := range []bool{false, true} {
+		t.Run(msfName(big), func(t *testing.T) {
+			streams := testStreams()
+			buf := newTestMSF(big, streams...).image()
+			file, err := Open(bytes.NewReader(buf), int64(len(buf)), nil)
+			if err != nil {
+				t.Fatalf("unable to open MSF image; %+v", err)
+			}
+			if got, want := int(file.StreamTbl.NStreams), len(streams); got != want {
+				t.Fatalf("number of streams mismatch; expected %d, got %d", want, got)
+			}
+			for streamNum, want := range streams {
+				if want == nil {
+					if !file.StreamTbl.StreamInfos[streamNum].IsNil() {
+						t.Errorf("stream %d: expected nil stream", streamNum)
+					}
+					continue
+				}
+				got, err := file.ReadStream(StreamNumber(streamNum))
+				if err != nil {
+					t.Errorf("stream %d: unable to read stream; %+v", streamNum, err)
+					continue
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("stream %d: contents mismatch", streamNum)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenMalformed(t *testing.T) {
+	golden := []struct {
+		// Test case name.
+		name string
+		// Corrupts the given MSF image before encoding.
+		corrupt func(m *testMSF)
+		// Truncates the encoded MSF image to the given size in bytes; or 0 to
+		// keep the entire image.
+		truncate int
+	}{
+		{
+			name: "page number out of range",
+			corrupt: func(m *testMSF) {
+				m.pageNumMaps[2][1] = 1000
+			},
+		},
+		{
+			name: "page number past end of 16-bit range",
+			corrupt: func(m *testMSF) {
+				m.pageNumMaps[0][0] = 0xFFFF
+			},
+		},
+		{
+			name: "page reserved for free page map",
+			corrupt: func(m *testMSF) {
+				m.pageNumMaps[0][0] = 1
+			},
+		},
+		{
+			name: "overlapping streams",
+			corrupt: func(m *testMSF) {
+				m.pageNumMaps[2][2] = m.pageNumMaps[0][0]
+			},
+		},
+		{
+			name: "stream page repeated within stream",
+			corrupt: func(m *testMSF) {
+				m.pageNumMaps[2][1] = m.pageNumMaps[2][0]
+			},
+		},
+		{
+			name: "negative stream size",
+			corrupt: func(m *testMSF) {
+				m.sizes[0] = -5
+			},
+		},
+		{
+			name: "stream size exceeds file",
+			corrupt: func(m *testMSF) {
+				m.sizes[2] = 0x7FFFFFFF
+			},
+		},
+		{
+			name: "stream table truncated within stream sizes",
+			corrupt: func(m *testMSF) {
+				m.streamTblSize = 6
+			},
+		},
+		{
+			name: "stream table truncated within page number maps",
+			corrupt: func(m *testMSF) {
+				// Number of streams, and stream sizes; omits page number maps.
+				m.streamTblSize = int32(4 + 8*len(m.sizes))
+				if m.big {
+					m.streamTblSize = int32(4 + 4*len(m.sizes))
+				}
+			},
+		},
+		{
+			name: "negative stream table size",
+			corrupt: func(m *testMSF) {
+				m.streamTblSize = -8
+			},
+		},
+		{
+			name: "number of pages exceeds file size",
+			corrupt: func(m *testMSF) {
+				m.npages = 200
+			},
+		},
+		{
+			name:     "file truncated",
+			truncate: 3*testPageSize + 20,
+		},
+		{
+			name:     "file truncated within MSF header",
+			truncate: 40,
+		},
+	}
+	for _, big := range []bool{false, true} {
+		for _, g := range golden {
+			t.Run(msfName(big)+"/"+g.name, func(t *testing.T) {
+				m := newTestMSF(big, testStreams()...)
+				if g.corrupt != nil {
+					g.corrupt(m)
+				}
+				buf := m.image()
+				if g.truncate != 0 {
+					buf = buf[:g.truncate]
+				}
+				_, err := Open(bytes.NewReader(buf), int64(len(buf)), nil)
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				t.Logf("error: %v", err)
+				var fe *FormatError
+				if !errors.As(err, &fe) {
+					t.Errorf("expected *FormatError, got %T: %v", errors.Cause(err), err)
+				}
+			})
+		}
+	}
+}
+
+func TestOpenMalformedBlockMap(t *testing.T) {
+	m := newTestMSF(true, testStreams()...)
+	m.pageNumMapPageNum = 1000
+	buf := m.image()
+	if _, err := Open(bytes.NewReader(buf), int64(len(buf)), nil); err == nil {
+		t.Fatalf("expected error for out-of-range page number map page, got nil")
+	}
+	// The page number map of the stream table is stored in the last page of
+	// test MSF images.
+	buf = newTestMSF(true, testStreams()...).image()
+	binary.LittleEndian.PutUint32(buf[len(buf)-testPageSize:], 1000)
+	_, err := Open(bytes.NewReader(buf), int64(len(buf)), nil)
+	if err == nil {
+		t.Fatalf("expected error for out-of-range page of stream table, got nil")
+	}
+	t.Logf("error: %v", err)
+}
+
+func TestOpenMalformedRecover(t *testing.T) {
+	// In recovery mode, invalid page numbers of streams are recorded as
+	// diagnostics, and reported when reading the affected streams.
+	for _, big := range []bool{false, true} {
+		t.Run(msfName(big), func(t *testing.T) {
+			m := newTestMSF(big, testStreams()...)
+			m.pageNumMaps[2][1] = 1000
+			buf := m.image()
+			file, err := Open(bytes.NewReader(buf), int64(len(buf)), &ParseOptions{Recover: true})
+			if err != nil {
+				t.Fatalf("unable to open MSF image in recovery mode; %+v", err)
+			}
+			if len(file.Diagnostics()) == 0 {
+				t.Errorf("expected diagnostics, got none")
+			}
+			if _, err := file.ReadStream(0); err != nil {
+				t.Errorf("unable to read valid stream 0; %+v", err)
+			}
+			if _, err := file.ReadStream(2); err == nil {
+				t.Errorf("expected error reading stream 2 with invalid page number, got nil")
+			}
+			if err := file.ParseStreams(); err != nil {
+				t.Errorf("unable to parse streams in recovery mode; %+v", err)
+			}
+		})
+	}
+}
+
+func TestIsReservedPage(t *testing.T) {
+	// Free page maps are repeated in every interval of PageSize pages in MSF
+	// 7.00, but not in MSF 2.00.
+	golden := []struct {
+		pageNum uint32
+		want200 bool
+		want700 bool
+	}{
+		{pageNum: 0, want200: true, want700: true},
+		{pageNum: 1, want200: true, want700: true},
+		{pageNum: 2, want200: true, want700: true},
+		{pageNum: 3, want200: false, want700: false},
+		{pageNum: testPageSize, want200: false, want700: false},
+		{pageNum: testPageSize + 1, want200: false, want700: true},
+		{pageNum: 2*testPageSize + 2, want200: false, want700: true},
+	}
+	for _, g := range golden {
+		for _, version := range []MSFVersion{MSFVersion200, MSFVersion700} {
+			file := &File{FileHdr: &MSFHeader{Version: version, PageSize: testPageSize}}
+			want := g.want200
+			if version == MSFVersion700 {
+				want = g.want700
+			}
+			if got := file.isReservedPage(g.pageNum); got != want {
+				t.Errorf("%v: page %d: reserved mismatch; expected %v, got %v", version, g.pageNum, want, got)
+			}
+		}
+	}
+}
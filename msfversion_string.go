@@ -0,0 +1,25 @@
+// Code generated by "stringer -linecomment -type MSFVersion"; DO NOT EDIT.
+
+package pdb
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[MSFVersion200-1]
+	_ = x[MSFVersion700-2]
+}
+
+const _MSFVersion_name = "MSF 2.00MSF 7.00"
+
+var _MSFVersion_index = [...]uint8{0, 8, 16}
+
+func (i MSFVersion) String() string {
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_MSFVersion_index)-1 {
+		return "MSFVersion(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _MSFVersion_name[_MSFVersion_index[idx]:_MSFVersion_index[idx+1]]
+}
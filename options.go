@@ -0,0 +1,182 @@
+package pdb
+
+import (
+	"io/ioutil"
+	"log"
+	"runtime"
+)
+
+// ParseOptions specifies options for parsing PDB files. A nil *ParseOptions is
+// equivalent to the zero value, which decodes all supported streams and logs
+// no messages.
+type ParseOptions struct {
+	// Logger of warning messages (e.g. unsupported streams); no warnings are
+	// logged if nil.
+	Warn *log.Logger
+	// Logger of debug messages; no debug messages are logged if nil.
+	Debug *log.Logger
+	// Streams to decode eagerly by ParseFile and File.ParseStreams; all
+	// supported streams are decoded if zero. Use Open without calling
+	// File.ParseStreams to only parse the MSF header and stream table.
+	Decode DecodeMask
+	// Report an error for streams not supported by the parser, rather than
+	// logging a warning.
+	Strict bool
+	// Recovery mode; parse each stream and each record independently, keeping
+	// undecodable records as raw bytes and recording problems in
+	// File.Diagnostics rather than aborting the parse. Recover takes precedence
+	// over Strict.
+	Recover bool
+	// Maximum number of streams decoded in parallel by ParseFile and
+	// File.ParseStreams; GOMAXPROCS if zero or negative. Use 1 to decode
+	// streams sequentially.
+	Concurrency int
+	// Progress is called to report the progress of decoding streams; no
+	// progress is reported if nil. As streams are decoded concurrently,
+	// Progress must be safe for concurrent use.
+	Progress func(p Progress)
+}
+
+// Progress records the progress of decoding a stream.
+type Progress struct {
+	// Stream number.
+	StreamNum StreamNumber
+	// Stream name (e.g. "TPI stream").
+	StreamName string
+	// Number of bytes of the stream processed.
+	Bytes int64
+	// Size in bytes of the stream.
+	Size int64
+	// Number of records decoded; or zero if the stream has no records.
+	Records int
+	// Decoding of the stream has completed.
+	Done bool
+}
+
+// DecodeMask is a bitmask of streams to decode eagerly.
+type DecodeMask uint32
+
+// Decode masks.
+const (
+	// Previous stream table (stream 0).
+	DecodePrevStreamTable DecodeMask = 1 << iota
+	// PDB stream (stream 1); contains the PDB header.
+	DecodePDBStream
+	// TPI stream (stream 2).
+	DecodeTPIStream
+	// DBI stream (stream 3).
+	DecodeDBIStream
+	// IPI stream (stream 4).
+	DecodeIPIStream
+	// String table ("/names" stream).
+	DecodeStringTable
+	// Link info ("/LinkInfo" stream).
+	DecodeLinkInfo
+	// Source header block ("/src/headerblock" stream).
+	DecodeSourceHeaderBlock
+	// Source Link ("sourcelink" stream).
+	DecodeSourceLink
+	// Source server ("srcsrv" stream).
+	DecodeSrcSrv
+
+	// All supported streams.
+	DecodeAll = DecodePrevStreamTable | DecodePDBStream | DecodeTPIStream | DecodeDBIStream | DecodeIPIStream | DecodeStringTable | DecodeLinkInfo | DecodeSourceHeaderBlock | DecodeSourceLink | DecodeSrcSrv
+)
+
+// discard is a logger which discards all messages.
+var discard = log.New(ioutil.Discard, "", 0)
+
+// initOptions initializes the parse options of the given PDB file, using default
+// values for unset options.
+func (file *File) initOptions(opts *ParseOptions) {
+	if opts != nil {
+		file.opts = *opts
+	}
+	if file.opts.Decode == 0 {
+		file.opts.Decode = DecodeAll
+	}
+	file.warn = file.opts.Warn
+	if file.warn == nil {
+		file.warn = discard
+	}
+	file.dbg = file.opts.Debug
+	if file.dbg == nil {
+		file.dbg = discard
+	}
+}
+
+// decodeMask returns the decode mask of the stream with the given stream
+// number; or zero if not supported.
+func (file *File) decodeMask(streamNum int) DecodeMask {
+	switch StreamID(streamNum) {
+	case StreamIDPrevStreamTable:
+		return DecodePrevStreamTable
+	case StreamIDPDBStream:
+		return DecodePDBStream
+	case StreamIDTPIStream:
+		return DecodeTPIStream
+	case StreamIDDBIStream:
+		return DecodeDBIStream
+	case StreamIDIPIStream:
+		return DecodeIPIStream
+	}
+	switch file.streamMetaName(StreamNumber(streamNum)) {
+	case StringTableStreamName:
+		return DecodeStringTable
+	case LinkInfoStreamName:
+		return DecodeLinkInfo
+	case SourceHeaderBlockStreamName:
+		return DecodeSourceHeaderBlock
+	case SourceLinkStreamName:
+		return DecodeSourceLink
+	case SrcSrvStreamName:
+		return DecodeSrcSrv
+	}
+	return 0
+}
+
+// concurrency returns the maximum number of streams to decode in parallel.
+func (file *File) concurrency() int {
+	if file.opts.Concurrency > 0 {
+		return file.opts.Concurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// progressInterval specifies the number of records decoded between progress
+// reports.
+const progressInterval = 1024
+
+// progress reports the progress of decoding the stream of the given stream
+// reader, as specified by opts.Progress.
+func (file *File) progress(sr *StreamReader, n int64, records int, done bool) {
+	if file.opts.Progress == nil || sr.streamNum == -1 {
+		return
+	}
+	file.opts.Progress(Progress{
+		StreamNum:  StreamNumber(sr.streamNum),
+		StreamName: sr.name,
+		Bytes:      n,
+		Size:       sr.size,
+		Records:    records,
+		Done:       done,
+	})
+}
+
+// recordCount returns the number of records of the given decoded stream; or
+// zero if the stream has no records.
+func recordCount(stream Stream) int {
+	switch stream := stream.(type) {
+	case *TPIStream:
+		return len(stream.Types)
+	case *StringTable:
+		return int(stream.NameCount)
+	case *SourceHeaderBlock:
+		return len(stream.Sources)
+	case *SourceLink:
+		return len(stream.Documents)
+	case *SrcSrv:
+		return len(stream.Files)
+	}
+	return 0
+}
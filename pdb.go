@@ -9,27 +9,20 @@ package pdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
-	"encoding/hex"
 	"io"
 	"io/ioutil"
 	"log"
 	"math"
-	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 
-	"github.com/mewkiz/pkg/term"
 	"github.com/pkg/errors"
 )
 
-var (
-	// dbg is a logger with the "pdb:" prefix which logs debug messages to standard
-	// error.
-	dbg = log.New(os.Stderr, term.CyanBold("pdb:")+" ", 0)
-	// warn is a logger with the "pdb:" prefix which logs warning messages to
-	// standard error.
-	warn = log.New(os.Stderr, term.RedBold("pdb:")+" ", 0)
-)
-
 // From https://github.com/microsoft/microsoft-pdb
 //
 //    +============+==============================+=====================================================================+
@@ -47,89 +40,339 @@ var (
 //    +------------+------------------------------+---------------------------------------------------------------------+
 
 // File is a PDB file.
+//
+// Once opened and parsed, a File is safe for concurrent read-only use by
+// multiple goroutines; e.g. its exported fields may be read and ReadStream,
+// StreamReader and PrevStreamTable may be called concurrently. Streams decoded
+// on demand in recovery mode may record diagnostics, which are therefore only
+// accessible through the Diagnostics method. ParseStreams and Close modify the
+// File and must not be called concurrently with other methods. Each
+// StreamReader keeps a read offset and must not be shared between goroutines
+// without synchronization.
 type File struct {
 	// File header of MSF.
 	FileHdr *MSFHeader
-	// Free page map.
+	// Free page map (active copy, as specified by
+	// FileHdr.FreePageMapPageNum).
 	FreePageMap *FreePageMap
+	// Alternate free page map (inactive copy, used for two-phase commits).
+	AltFreePageMap *FreePageMap
 	// Stream table.
 	StreamTbl *StreamTable
-	// Streams.
+	// Streams indexed by stream number, as decoded by ParseStreams; nil for
+	// nil streams, and *RawStream for streams not decoded.
 	Streams []Stream
 
-	// Contents of underlying PDB file.
-	Data []byte // TODO: rename to buf
+	// Underlying reader of PDB file contents.
+	r io.ReaderAt
+	// Size in bytes of underlying PDB file.
+	size int64
+	// closer releases the resources of the underlying reader on Close; or nil
+	// if owned by the caller.
+	closer io.Closer
+	// Parse options.
+	opts ParseOptions
+	// dbg is a logger of debug messages, as specified by opts.Debug.
+	dbg *log.Logger
+	// warn is a logger of warning messages, as specified by opts.Warn.
+	warn *log.Logger
+	// mu guards diags while streams are decoded concurrently.
+	mu sync.Mutex
+	// Problems encountered while parsing in recovery mode (see
+	// ParseOptions.Recover).
+	diags []Diagnostic
+	// Streams decoded on demand, indexed by stream number (see File.stream).
+	streamCache []cachedStream // length: StreamTbl.NStreams
+	// Previous stream table, decoded on demand (see File.PrevStreamTable).
+	prevStreamTblOnce sync.Once
+	prevStreamTbl     *StreamTable
+	prevStreamTblErr  error
+}
+
+// ParseFile parses the given PDB file, reading from pdbPath. The contents of
+// streams are decoded eagerly, as specified by opts.Decode; use Open to decode
+// stream contents on demand. A nil opts uses default options.
+func ParseFile(pdbPath string, opts *ParseOptions) (*File, error) {
+	file, err := ParseFileContext(context.Background(), pdbPath, opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return file, nil
 }
 
-// ParseFile parses the given PDB file, reading from pdbPath.
-func ParseFile(pdbPath string) (*File, error) {
+// ParseFileContext parses the given PDB file, reading from pdbPath, as with
+// ParseFile. Parsing is aborted with the error of ctx if ctx is cancelled.
+func ParseFileContext(ctx context.Context, pdbPath string, opts *ParseOptions) (*File, error) {
 	// Read PDB file contents.
 	buf, err := ioutil.ReadFile(pdbPath)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	file, err := Open(&memReader{data: buf}, int64(len(buf)), opts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Parse streams.
+	if err := file.ParseStreamsContext(ctx); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return file, nil
+}
+
+// Open opens the PDB file of the given size in bytes, reading from r. The MSF
+// file header, free page map and stream table are parsed eagerly, while the
+// contents of streams are only read from r when requested (e.g. through
+// ReadStream or ParseStreams).
+//
+// The underlying reader r must remain valid for the lifetime of the returned
+// file. A nil opts uses default options.
+func Open(r io.ReaderAt, size int64, opts *ParseOptions) (*File, error) {
 	file := &File{
-		Data: buf,
+		r:    r,
+		size: size,
 	}
+	file.initOptions(opts)
 	// Parse MSF file header.
-	msfHdr, err := parseMSFHeader(bytes.NewReader(file.Data))
+	hdrReader := io.NewSectionReader(r, 0, size)
+	msfHdr, err := parseMSFHeader(hdrReader)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		fileOff, _ := hdrReader.Seek(0, io.SeekCurrent)
+		return nil, errors.WithStack(newFormatError("MSF header", fileOff, err))
 	}
 	file.FileHdr = msfHdr
-	// Parse free page map.
-	freePageMapData := file.readPage(int(file.FileHdr.FreePageMapPageNum))
-	file.FreePageMap = &FreePageMap{
-		PageBits: freePageMapData,
+	if err := file.validateMSFHeader(); err != nil {
+		return nil, errors.WithStack(newFormatError("MSF header", -1, err))
+	}
+	// Parse page number map of stream table (MSF 7.00).
+	if file.FileHdr.Version == MSFVersion700 {
+		if err := file.parseStreamTblPageNumMap(); err != nil {
+			fileOff := int64(file.FileHdr.PageNumMapPageNum) * int64(file.FileHdr.PageSize)
+			return nil, errors.WithStack(newFormatError("stream table page number map", fileOff, err))
+		}
+	}
+	// Parse free page maps.
+	fpm, err := file.readFreePageMap(file.FileHdr.FreePageMapPageNum)
+	if err != nil {
+		return nil, errors.WithStack(newFormatError("free page map", -1, err))
 	}
+	file.FreePageMap = fpm
+	altFPM, err := file.readFreePageMap(altFreePageMapPageNum(file.FileHdr.FreePageMapPageNum))
+	if err != nil {
+		return nil, errors.WithStack(newFormatError("alternate free page map", -1, err))
+	}
+	file.AltFreePageMap = altFPM
 	// Parse stream table.
-	streamTblData := file.readStreamTable()
-	streamTbl, err := file.parseStreamTable(bytes.NewReader(streamTblData))
+	streamTblReader := file.newStreamReader("stream table", file.FileHdr.PageNumMap, file.FileHdr.StreamTblInfo.Size)
+	streamTbl, err := file.parseStreamTable(streamTblReader)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, errors.WithStack(streamTblReader.formatError(err))
 	}
 	file.StreamTbl = streamTbl
-	// Parse streams.
-	for streamNum := 0; streamNum < int(file.StreamTbl.NStreams); streamNum++ {
-		if err := file.parseStream(streamNum); err != nil {
+	file.streamCache = make([]cachedStream, streamTbl.NStreams)
+	return file, nil
+}
+
+// ParseStreams parses the contents of each stream of the PDB file selected by
+// the Decode parse option, storing the result in file.Streams.
+//
+// Streams are decoded in parallel by up to ParseOptions.Concurrency
+// goroutines. Each decoded stream is stored at the index of its stream number,
+// independent of scheduling. Unless in recovery mode, the error of the
+// lowest-numbered stream which failed to decode is returned; in recovery mode,
+// streams which failed to decode are stored as *RawStream, and diagnostics
+// recorded while decoding are ordered by stream number.
+func (file *File) ParseStreams() error {
+	if err := file.ParseStreamsContext(context.Background()); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// ParseStreamsContext parses the contents of each stream of the PDB file, as
+// with ParseStreams. Cancellation of ctx is checked between streams, pages and
+// records; if cancelled, decoding is aborted and the error of ctx is returned,
+// also in recovery mode.
+func (file *File) ParseStreamsContext(ctx context.Context) error {
+	nstreams := int(file.StreamTbl.NStreams)
+	streams := make([]Stream, nstreams)
+	errs := make([]error, nstreams)
+	file.mu.Lock()
+	ndiags := len(file.diags)
+	file.mu.Unlock()
+	var (
+		// Stream number of the last stream handed to a worker; incremented
+		// atomically.
+		next int64 = -1
+		// Lowest stream number which failed to decode; updated atomically.
+		failed = int64(nstreams)
+	)
+	nworkers := file.concurrency()
+	if nworkers > nstreams {
+		nworkers = nstreams
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < nworkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				// Stream numbers are handed out in increasing order, so no
+				// stream past the first failure remains to be decoded.
+				streamNum := atomic.AddInt64(&next, 1)
+				if streamNum >= int64(nstreams) || streamNum > atomic.LoadInt64(&failed) || ctx.Err() != nil {
+					return
+				}
+				stream, err := file.parseStream(ctx, int(streamNum))
+				if err != nil {
+					errs[streamNum] = err
+					for !file.opts.Recover {
+						cur := atomic.LoadInt64(&failed)
+						if streamNum >= cur || atomic.CompareAndSwapInt64(&failed, cur, streamNum) {
+							break
+						}
+					}
+					continue
+				}
+				streams[streamNum] = stream
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	for streamNum, err := range errs {
+		if err == nil {
+			continue
+		}
+		if !file.opts.Recover {
+			return errors.WithStack(err)
+		}
+		file.addDiagnostic(SeverityError, err)
+		streams[streamNum] = file.newRawStream(StreamNumber(streamNum))
+	}
+	file.Streams = streams
+	// Diagnostics of different streams are recorded in scheduling order;
+	// order them by stream number, keeping the order within each stream.
+	file.mu.Lock()
+	defer file.mu.Unlock()
+	diags := file.diags[ndiags:]
+	sort.SliceStable(diags, func(i, j int) bool {
+		return diags[i].StreamNum < diags[j].StreamNum
+	})
+	return nil
+}
+
+// readPage returns the contents of the given page, reading from the underlying
+// reader of the PDB file. If the underlying reader holds the file in memory
+// (e.g. OpenMmap), the returned slice aliases its contents and must not be
+// modified.
+func (file *File) readPage(pageNum int) ([]byte, error) {
+	start, err := file.pageOffset(uint32(pageNum))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if s, ok := file.r.(slicer); ok {
+		buf, err := s.slice(start, int64(file.FileHdr.PageSize))
+		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+		return buf, nil
 	}
-	return file, nil
+	buf := make([]byte, file.FileHdr.PageSize)
+	if _, err := file.r.ReadAt(buf, start); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf, nil
 }
 
-// readPage returns the contents of the given page.
-func (file *File) readPage(pageNum int) []byte {
-	pageSize := int(file.FileHdr.PageSize)
-	start := pageNum * pageSize
-	end := start + pageSize
-	return file.Data[start:end]
+// pageOffset returns the offset in bytes within the PDB file of the given page.
+func (file *File) pageOffset(pageNum uint32) (int64, error) {
+	if err := file.validatePageNum(pageNum); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return int64(pageNum) * int64(file.FileHdr.PageSize), nil
+}
+
+// validatePageNum validates the given page number against the number of pages
+// of the MSF and the size of the PDB file.
+func (file *File) validatePageNum(pageNum uint32) error {
+	if pageNum >= file.FileHdr.NPages {
+		return errors.Errorf("invalid page number %d; expected < %d", pageNum, file.FileHdr.NPages)
+	}
+	pageSize := int64(file.FileHdr.PageSize)
+	if end := (int64(pageNum) + 1) * pageSize; end > file.size {
+		return errors.Errorf("invalid page number %d; page end (offset %d) exceeds file size (%d bytes)", pageNum, end, file.size)
+	}
+	return nil
+}
+
+// isReservedPage reports whether the given page is reserved for the MSF header
+// or the free page maps.
+func (file *File) isReservedPage(pageNum uint32) bool {
+	if pageNum == 0 {
+		return true
+	}
+	// The free page maps of MSF 2.00 are stored in pages 1 and 2, while those
+	// of MSF 7.00 are repeated in every interval of PageSize pages.
+	if file.FileHdr.Version != MSFVersion700 {
+		return pageNum <= 2
+	}
+	switch pageNum % uint32(file.FileHdr.PageSize) {
+	case 1, 2:
+		return true
+	}
+	return false
 }
 
 // MSF signatures.
 const (
+	// Signature of MSF 2.00 (small MSF).
 	msfSignature = "Microsoft C/C++ program database 2.00\r\n\x1a\x4a\x47\x00\x00"
-	// TODO: define signature for MSFBig.
+	// Signature of MSF 7.00 (big MSF).
+	msfSignatureBig = "Microsoft C/C++ MSF 7.00\r\n\x1a\x44\x53\x00\x00\x00"
+)
+
+//go:generate stringer -linecomment -type MSFVersion
+
+// MSFVersion specifies the container format of a multistream file (MSF).
+type MSFVersion uint8
+
+// MSF versions.
+const (
+	// MSF 2.00 (small MSF) with 16-bit page numbers.
+	MSFVersion200 MSFVersion = iota + 1 // MSF 2.00
+	// MSF 7.00 (big MSF) with 32-bit page numbers.
+	MSFVersion700 // MSF 7.00
 )
 
 // MSFHeader is the header of a multistream file (MSF). The MSF header is always
 // at page 0.
 //
 // ref: https://llvm.org/docs/PDB/MsfFile.html#the-superblock
-// ref: MSF_HDR
+// ref: MSF_HDR (MSF 2.00)
+// ref: BIGMSF_HDR (MSF 7.00)
 type MSFHeader struct {
-	// File format identifier.
-	Magic [44]byte
+	// File format identifier; 44 bytes for MSF 2.00 and 32 bytes for MSF 7.00.
+	Magic []byte
+	// MSF container format (derived from Magic).
+	Version MSFVersion
 	// Page size in bytes.
 	PageSize int32
 	// Page number of free page map.
-	FreePageMapPageNum uint16
+	FreePageMapPageNum uint32 // uint16 in MSF 2.00
 	// Number of pages.
-	NPages uint16
+	NPages uint32 // uint16 in MSF 2.00
 	// Stream information about the stream table.
 	StreamTblInfo StreamInfo
-	// Maps from stream page number to page number.
-	PageNumMap []uint16 // length: math.Ceil(msfHdr.StreamTblInfo.Size / msfHdr.PageSize)
+	// Page number of the page holding PageNumMap (MSF 7.00 only).
+	//
+	// ref: https://llvm.org/docs/PDB/MsfFile.html#the-superblock (BlockMapAddr)
+	PageNumMapPageNum uint32
+	// Maps from stream page number to page number of the stream table. In MSF
+	// 2.00, PageNumMap directly follows the header; in MSF 7.00 it is stored in
+	// the page PageNumMapPageNum.
+	PageNumMap []uint32 // length: math.Ceil(msfHdr.StreamTblInfo.Size / msfHdr.PageSize); uint16 elements in MSF 2.00
 	// align until page boundry.
 }
 
@@ -137,85 +380,215 @@ type MSFHeader struct {
 func parseMSFHeader(r io.Reader) (*MSFHeader, error) {
 	// Magic.
 	msfHdr := &MSFHeader{}
-	if err := binary.Read(r, binary.LittleEndian, &msfHdr.Magic); err != nil {
+	msfHdr.Magic = make([]byte, len(msfSignatureBig))
+	if _, err := io.ReadFull(r, msfHdr.Magic); err != nil {
 		return nil, errors.WithStack(err)
 	}
-	magic := string(msfHdr.Magic[:])
+	if string(msfHdr.Magic) == msfSignatureBig {
+		msfHdr.Version = MSFVersion700
+		if err := parseMSFHeaderBig(r, msfHdr); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return msfHdr, nil
+	}
+	rest := make([]byte, len(msfSignature)-len(msfSignatureBig))
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	msfHdr.Magic = append(msfHdr.Magic, rest...)
+	magic := string(msfHdr.Magic)
 	if magic != msfSignature {
-		return nil, errors.Errorf("invalid MSF signature; expected %q, got %q", msfSignature, magic)
+		return nil, errors.Errorf("invalid MSF signature; expected %q or %q, got %q", msfSignature, msfSignatureBig, magic)
+	}
+	msfHdr.Version = MSFVersion200
+	if err := parseMSFHeaderSmall(r, msfHdr); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return msfHdr, nil
+}
+
+// validateMSFHeader validates the MSF file header against the size of the PDB
+// file.
+func (file *File) validateMSFHeader() error {
+	msfHdr := file.FileHdr
+	// NPages.
+	if size := int64(msfHdr.NPages) * int64(msfHdr.PageSize); size > file.size {
+		return errors.Errorf("invalid number of pages %d; size of pages (%d bytes) exceeds file size (%d bytes)", msfHdr.NPages, size, file.size)
 	}
+	// FreePageMapPageNum.
+	switch msfHdr.FreePageMapPageNum {
+	case 1, 2:
+		// valid free page map page number.
+	default:
+		return errors.Errorf("invalid free page map page number %d; expected 1 or 2", msfHdr.FreePageMapPageNum)
+	}
+	// PageNumMap.
+	for _, pageNum := range msfHdr.PageNumMap {
+		if err := file.validatePageNum(pageNum); err != nil {
+			return errors.Wrap(err, "invalid page of stream table")
+		}
+	}
+	return nil
+}
+
+// validatePageSize validates the page size and stream table size of the given
+// MSF file header, as required to determine the length of PageNumMap.
+func validatePageSize(msfHdr *MSFHeader) error {
+	// PageSize.
+	switch msfHdr.PageSize {
+	case 512, 1024, 2048, 4096, 8192, 16384, 32768:
+		// valid page size.
+	default:
+		return errors.Errorf("invalid page size %d; expected power of two in range [512, 32768]", msfHdr.PageSize)
+	}
+	// StreamTblInfo.
+	if size := int64(msfHdr.StreamTblInfo.Size); size < 4 || size > int64(msfHdr.NPages)*int64(msfHdr.PageSize) {
+		return errors.Errorf("invalid stream table size %d", msfHdr.StreamTblInfo.Size)
+	}
+	return nil
+}
+
+// parseMSFHeaderSmall parses the remainder of the given MSF 2.00 file header
+// (following the magic), reading from r.
+func parseMSFHeaderSmall(r io.Reader, msfHdr *MSFHeader) error {
 	// PageSize.
 	if err := binary.Read(r, binary.LittleEndian, &msfHdr.PageSize); err != nil {
-		return nil, errors.WithStack(err)
+		return errors.WithStack(err)
 	}
 	// FreePageMapPageNum.
-	if err := binary.Read(r, binary.LittleEndian, &msfHdr.FreePageMapPageNum); err != nil {
-		return nil, errors.WithStack(err)
+	var freePageMapPageNum uint16
+	if err := binary.Read(r, binary.LittleEndian, &freePageMapPageNum); err != nil {
+		return errors.WithStack(err)
 	}
+	msfHdr.FreePageMapPageNum = uint32(freePageMapPageNum)
 	// NPages.
-	if err := binary.Read(r, binary.LittleEndian, &msfHdr.NPages); err != nil {
-		return nil, errors.WithStack(err)
+	var npages uint16
+	if err := binary.Read(r, binary.LittleEndian, &npages); err != nil {
+		return errors.WithStack(err)
 	}
+	msfHdr.NPages = uint32(npages)
 	// StreamTblInfo.
 	if err := binary.Read(r, binary.LittleEndian, &msfHdr.StreamTblInfo); err != nil {
-		return nil, errors.WithStack(err)
+		return errors.WithStack(err)
 	}
 	// PageNumMap.
-	streamTblNPages := int(math.Ceil(float64(msfHdr.StreamTblInfo.Size) / float64(msfHdr.PageSize))) // number of pages used by stream table.
-	msfHdr.PageNumMap = make([]uint16, streamTblNPages)
-	if err := binary.Read(r, binary.LittleEndian, &msfHdr.PageNumMap); err != nil {
-		return nil, errors.WithStack(err)
+	if err := validatePageSize(msfHdr); err != nil {
+		return errors.WithStack(err)
+	}
+	streamTblNPages := pageCount(msfHdr.StreamTblInfo.Size, msfHdr.PageSize) // number of pages used by stream table.
+	pageNumMap := make([]uint16, streamTblNPages)
+	if err := binary.Read(r, binary.LittleEndian, &pageNumMap); err != nil {
+		return errors.WithStack(err)
+	}
+	msfHdr.PageNumMap = make([]uint32, streamTblNPages)
+	for i, pageNum := range pageNumMap {
+		msfHdr.PageNumMap[i] = uint32(pageNum)
 	}
 	// TODO: validate alignment until page boundry to be all zero?
-	return msfHdr, nil
+	return nil
+}
+
+// parseMSFHeaderBig parses the remainder of the given MSF 7.00 file header
+// (following the magic), reading from r. Note, PageNumMap is stored in a
+// separate page, and is parsed by File.parseStreamTblPageNumMap.
+//
+// ref: https://llvm.org/docs/PDB/MsfFile.html#the-superblock
+func parseMSFHeaderBig(r io.Reader, msfHdr *MSFHeader) error {
+	// PageSize.
+	if err := binary.Read(r, binary.LittleEndian, &msfHdr.PageSize); err != nil {
+		return errors.WithStack(err)
+	}
+	// FreePageMapPageNum.
+	if err := binary.Read(r, binary.LittleEndian, &msfHdr.FreePageMapPageNum); err != nil {
+		return errors.WithStack(err)
+	}
+	// NPages.
+	if err := binary.Read(r, binary.LittleEndian, &msfHdr.NPages); err != nil {
+		return errors.WithStack(err)
+	}
+	// StreamTblInfo.
+	if err := binary.Read(r, binary.LittleEndian, &msfHdr.StreamTblInfo); err != nil {
+		return errors.WithStack(err)
+	}
+	// PageNumMapPageNum.
+	if err := binary.Read(r, binary.LittleEndian, &msfHdr.PageNumMapPageNum); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := validatePageSize(msfHdr); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// parseStreamTblPageNumMap parses the page number map of the stream table of
+// an MSF 7.00 file, reading from the page MSFHeader.PageNumMapPageNum.
+func (file *File) parseStreamTblPageNumMap() error {
+	streamTblNPages := pageCount(file.FileHdr.StreamTblInfo.Size, file.FileHdr.PageSize) // number of pages used by stream table.
+	if max := int(file.FileHdr.PageSize) / 4; streamTblNPages > max {
+		return errors.Errorf("stream table too large; page number map of %d pages exceeds capacity of page (%d page numbers)", streamTblNPages, max)
+	}
+	pageData, err := file.readPage(int(file.FileHdr.PageNumMapPageNum))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	file.FileHdr.PageNumMap = make([]uint32, streamTblNPages)
+	if err := binary.Read(bytes.NewReader(pageData), binary.LittleEndian, &file.FileHdr.PageNumMap); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, pageNum := range file.FileHdr.PageNumMap {
+		if err := file.validatePageNum(pageNum); err != nil {
+			return errors.Wrap(err, "invalid page of stream table")
+		}
+	}
+	return nil
+}
+
+// pageCount returns the number of pages of the given page size required to
+// store size bytes.
+func pageCount(size, pageSize int32) int {
+	return int(math.Ceil(float64(size) / float64(pageSize)))
 }
 
 // StreamInfo specifies stream information.
 //
 // ref: SI_PERSIST
 type StreamInfo struct {
-	// Size in bytes of stream table.
+	// Size in bytes of stream; or nilStreamSize (-1) for nil streams.
 	Size int32
 	// ref: SI_PERSIST.mpspnpn
+	//
+	// Not present for stream table entries of MSF 7.00.
 	Unknown int32
 }
 
-// FreePageMap specifies what pages are used/unused.
+// nilStreamSize is the stream size used to mark unused stream slots (nil
+// streams) in the stream table.
 //
-// ref: https://llvm.org/docs/PDB/MsfFile.html#the-free-block-map
-// ref: FPM
-type FreePageMap struct {
-	// Each bit specifies whether the corresponding page is used or unused.
-	//
-	//    0 = used
-	//    1 = unused
-	PageBits []byte // length: msfHdr.PageSize
-}
+// ref: cbNil
+const nilStreamSize = -1
 
-// IsFree reports whether the given page number is unused.
-func (fpm *FreePageMap) IsFree(pageNum int) bool {
-	i := pageNum / 8
-	j := pageNum % 8
-	mask := uint8(1) << j
-	return fpm.PageBits[i]&mask != 0
+// IsNil reports whether the stream is a nil stream (i.e. an unused stream slot
+// of the stream table). Note that nil streams are distinct from zero-length
+// streams.
+func (info StreamInfo) IsNil() bool {
+	return info.Size == nilStreamSize
 }
 
-// readStreamTable reads the contents of the stream table, concatenating its
-// pages together.
-func (file *File) readStreamTable() []byte {
-	streamTblNPages := int(math.Ceil(float64(file.FileHdr.StreamTblInfo.Size) / float64(file.FileHdr.PageSize))) // number of pages used by stream table.
-	var streamTblData []byte
-	for streamPageNum := 0; streamPageNum < streamTblNPages; streamPageNum++ {
-		pageNum := int(file.FileHdr.PageNumMap[streamPageNum])
-		pageData := file.readPage(pageNum)
-		streamTblData = append(streamTblData, pageData...)
-	}
-	return streamTblData[:file.FileHdr.StreamTblInfo.Size]
+// NPages returns the number of pages of the given page size used by the stream.
+func (info StreamInfo) NPages(pageSize int32) int {
+	if info.IsNil() {
+		return 0
+	}
+	return pageCount(info.Size, pageSize)
 }
 
 // StreamNumber is a stream index.
 type StreamNumber uint16
 
+// NilStreamNum is the stream number used to denote the absence of a stream
+// (e.g. in the TPI and DBI stream headers).
+const NilStreamNum StreamNumber = 0xFFFF
+
 //go:generate stringer -linecomment -type StreamID
 
 // StreamID specifies a fixed stream index.
@@ -228,62 +601,252 @@ const (
 	StreamIDPrevStreamTable StreamID = 0 // previous stream table
 	StreamIDPDBStream       StreamID = 1 // PDB stream
 	StreamIDTPIStream       StreamID = 2 // TPI stream
+	StreamIDDBIStream       StreamID = 3 // DBI stream
+	StreamIDIPIStream       StreamID = 4 // IPI stream
 )
 
-// readStreamData reads the contents of the stream with the given stream number,
-// concatenating its pages together.
-func (file *File) readStreamData(streamNum int) []byte {
-	streamInfo := file.StreamTbl.StreamInfos[streamNum]
-	pageNumMap := file.StreamTbl.PageNumMaps[streamNum]
-	var streamData []byte
-	for streamPageNum, pageNum := range pageNumMap {
-		_ = streamPageNum
-		pageData := file.readPage(int(pageNum))
-		streamData = append(streamData, pageData...)
-	}
-	return streamData[:streamInfo.Size]
+// ReadStream reads the contents of the stream with the given stream number.
+func (file *File) ReadStream(streamNum StreamNumber) ([]byte, error) {
+	sr, err := file.StreamReader(streamNum)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	streamData := make([]byte, sr.Size())
+	if _, err := io.ReadFull(sr, streamData); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return streamData, nil
 }
 
-// Stream is a stream of a PDB file.
-//
-// Stream is one of the following types.
-//
-//    *PDBStream
-// TODO: add more stream types.
-type Stream interface{}
+// DecodeStream decodes the contents of the stream with the given stream
+// number, independent of the Decode parse option; or returns nil if the stream
+// is nil. Streams not supported by the parser are returned as *RawStream.
+func (file *File) DecodeStream(streamNum StreamNumber) (Stream, error) {
+	stream, err := file.DecodeStreamContext(context.Background(), streamNum)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return stream, nil
+}
+
+// DecodeStreamContext decodes the contents of the stream with the given stream
+// number, as with DecodeStream. Cancellation of ctx is checked between pages
+// and records; if cancelled, decoding is aborted and the error of ctx is
+// returned.
+func (file *File) DecodeStreamContext(ctx context.Context, streamNum StreamNumber) (Stream, error) {
+	if int(streamNum) >= len(file.StreamTbl.StreamInfos) {
+		return nil, errors.Errorf("invalid stream number %d; expected < %d", streamNum, len(file.StreamTbl.StreamInfos))
+	}
+	sr, err := file.StreamReader(streamNum)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sr.ctx = ctx
+	stream, err := file.decodeStream(sr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return stream, nil
+}
 
-// parseStream parses the stream with the given stream number.
+// parseStream parses the stream with the given stream number; or returns nil
+// if the stream is nil. Streams not selected for decoding or not supported are
+// returned as *RawStream.
 //
 // ref: https://llvm.org/docs/PDB/index.html#streams
-func (file *File) parseStream(streamNum int) error {
-	dbg.Println("parseStream")
-	dbg.Println("   streamNum:", streamNum)
-	streamData := file.readStreamData(streamNum)
-	dbg.Print("   streamData:\n", hex.Dump(streamData))
+func (file *File) parseStream(ctx context.Context, streamNum int) (Stream, error) {
+	sr, err := file.StreamReader(StreamNumber(streamNum))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sr.ctx = ctx
+	file.dbg.Printf("parseStream: stream %d (%d bytes)", streamNum, sr.Size())
+	// Skip nil streams.
+	if file.StreamTbl.StreamInfos[streamNum].IsNil() {
+		file.dbg.Printf("skipping nil stream %d", streamNum)
+		return nil, nil
+	}
+	// Skip streams not selected for decoding.
+	if mask := file.decodeMask(streamNum); mask != 0 && file.opts.Decode&mask == 0 {
+		file.dbg.Printf("skipping stream %d not selected for decoding", streamNum)
+		return file.newRawStream(StreamNumber(streamNum)), nil
+	}
+	// The PDB stream is decoded once, as shared with PDBInfo, so that its
+	// diagnostics are only recorded once.
+	if StreamID(streamNum) == StreamIDPDBStream {
+		pdbStream, err := file.PDBInfo()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return pdbStream, nil
+	}
+	stream, err := file.decodeStream(sr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return stream, nil
+}
+
+// decodeStream decodes the contents of the stream of the given stream reader;
+// or returns nil if the stream is nil.
+func (file *File) decodeStream(sr *StreamReader) (Stream, error) {
+	streamNum := sr.streamNum
+	if file.StreamTbl.StreamInfos[streamNum].IsNil() {
+		return nil, nil
+	}
+	stream, err := file.decodeStreamContents(sr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	file.progress(sr, sr.size, recordCount(stream), true)
+	return stream, nil
+}
+
+// decodeStreamContents decodes the contents of the stream of the given stream
+// reader, dispatching on the stream number.
+func (file *File) decodeStreamContents(sr *StreamReader) (Stream, error) {
+	streamNum := sr.streamNum
 	switch StreamID(streamNum) {
 	// Previous stream table (old MSF stream table)
 	case StreamIDPrevStreamTable:
-		prevStreamTbl, err := file.parseStreamTable(bytes.NewReader(streamData))
+		prevStreamTbl, err := file.PrevStreamTable()
 		if err != nil {
-			return errors.WithStack(err)
+			if errors.Cause(err) == ErrNoPrevStreamTable {
+				return file.newRawStream(StreamNumber(streamNum)), nil
+			}
+			return nil, errors.WithStack(err)
 		}
-		file.Streams = append(file.Streams, prevStreamTbl)
+		return &PrevStreamTableStream{streamMeta: sr.meta(StreamKindPrevStreamTable), Tbl: prevStreamTbl}, nil
 	// PDB stream
 	case StreamIDPDBStream:
-		pdbStream, err := file.parsePDBStream(bytes.NewReader(streamData))
+		pdbStream, err := file.parsePDBStream(sr)
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, errors.WithStack(sr.formatError(err))
 		}
-		file.Streams = append(file.Streams, pdbStream)
+		pdbStream.streamMeta = sr.meta(StreamKindPDB)
+		return pdbStream, nil
 	// TPI stream
 	case StreamIDTPIStream:
-		tpiStream, err := file.parseTPIStream(bytes.NewReader(streamData))
+		tpiStream, err := file.parseTPIStream(sr)
 		if err != nil {
-			return errors.WithStack(err)
+			if isUnsupported(err) {
+				return file.unsupportedStream(sr, err)
+			}
+			return nil, errors.WithStack(sr.formatError(err))
+		}
+		tpiStream.streamMeta = sr.meta(StreamKindTPI)
+		return tpiStream, nil
+	// DBI stream
+	case StreamIDDBIStream:
+		dbiStream, err := file.parseDBIStream(sr)
+		if err != nil {
+			if isUnsupported(err) {
+				return file.unsupportedStream(sr, err)
+			}
+			return nil, errors.WithStack(sr.formatError(err))
+		}
+		dbiStream.streamMeta = sr.meta(StreamKindDBI)
+		return dbiStream, nil
+	// IPI stream
+	case StreamIDIPIStream:
+		// Stream 4 is only an IPI stream in PDB files produced by VC 11.0 and
+		// later.
+		if !file.hasIPIStream() {
+			return file.newRawStream(StreamNumber(streamNum)), nil
+		}
+		ipiStream, err := file.parseTPIStream(sr)
+		if err != nil {
+			if isUnsupported(err) {
+				return file.unsupportedStream(sr, err)
+			}
+			return nil, errors.WithStack(sr.formatError(err))
+		}
+		ipiStream.streamMeta = sr.meta(StreamKindIPI)
+		return ipiStream, nil
+	}
+	// Named streams.
+	name := file.streamMetaName(StreamNumber(streamNum))
+	switch name {
+	// String table
+	case StringTableStreamName:
+		strTbl, err := file.parseStringTable(sr)
+		if err != nil {
+			return nil, errors.WithStack(sr.formatError(err))
 		}
-		file.Streams = append(file.Streams, tpiStream)
+		strTbl.streamMeta = sr.meta(StreamKindStringTable)
+		return strTbl, nil
+	// Link info
+	case LinkInfoStreamName:
+		linkInfo, err := file.parseLinkInfo(sr)
+		if err != nil {
+			return nil, errors.WithStack(sr.formatError(err))
+		}
+		linkInfo.streamMeta = sr.meta(StreamKindLinkInfo)
+		return linkInfo, nil
+	// Source header block
+	case SourceHeaderBlockStreamName:
+		block, err := file.parseSourceHeaderBlock(sr)
+		if err != nil {
+			return nil, errors.WithStack(sr.formatError(err))
+		}
+		block.streamMeta = sr.meta(StreamKindSourceHeaderBlock)
+		return block, nil
+	// Source Link
+	case SourceLinkStreamName:
+		sourceLink, err := file.parseSourceLink(sr)
+		if err != nil {
+			return nil, errors.WithStack(sr.formatError(err))
+		}
+		sourceLink.streamMeta = sr.meta(StreamKindSourceLink)
+		return sourceLink, nil
+	// Source server
+	case SrcSrvStreamName:
+		srcSrv, err := file.parseSrcSrv(sr)
+		if err != nil {
+			return nil, errors.WithStack(sr.formatError(err))
+		}
+		srcSrv.streamMeta = sr.meta(StreamKindSrcSrv)
+		return srcSrv, nil
+	}
+	// Contents of injected source files are read using
+	// File.InjectedSourceContents.
+	if strings.HasPrefix(name, InjectedSourceStreamPrefix) {
+		return file.newRawStream(StreamNumber(streamNum)), nil
+	}
+	switch {
+	case file.opts.Recover:
+		file.addStreamDiagnostic(SeverityInfo, streamNum, -1, "support for stream number %d not yet implemented", streamNum)
+	case file.opts.Strict:
+		return nil, errors.Errorf("support for stream number %d not yet implemented", streamNum)
 	default:
-		warn.Printf("support for stream number %d not yet implemented", streamNum)
+		file.warn.Printf("support for stream number %d not yet implemented", streamNum)
 	}
-	return nil
+	return file.newRawStream(StreamNumber(streamNum)), nil
+}
+
+// unsupportedStream returns a raw stream for the stream of the given stream
+// reader, whose format is not yet supported as reported by err. The problem is
+// recorded as a diagnostic in recovery mode, reported as an error in strict
+// mode, and logged as a warning otherwise.
+func (file *File) unsupportedStream(sr *StreamReader, err error) (Stream, error) {
+	err = sr.formatError(err)
+	switch {
+	case file.opts.Recover:
+		file.addDiagnostic(SeverityWarning, err)
+	case file.opts.Strict:
+		return nil, errors.WithStack(err)
+	default:
+		file.warn.Print(err)
+	}
+	return file.newRawStream(StreamNumber(sr.streamNum)), nil
+}
+
+// hasIPIStream reports whether stream 4 of the PDB file is an IPI stream, as
+// specified by the feature codes of the PDB stream.
+func (file *File) hasIPIStream() bool {
+	pdbStream, err := file.PDBInfo()
+	if err != nil {
+		return false
+	}
+	return pdbStream.HasIPIStream()
 }
@@ -12,14 +12,46 @@ import (
 //
 // ref: https://llvm.org/docs/PDB/PdbStream.html
 type PDBStream struct {
+	streamMeta
 	// PDB stream header.
 	Hdr *PDBStreamHeader
 	// Map from stream name to stream number.
 	StreamNameMap *StreamNameMap
+	// Feature codes, in order of appearance.
+	Features []PDBFeature
 }
 
-// parsePDBStream parses the given PDB stream.
-func (file *File) parsePDBStream(r io.Reader) (*PDBStream, error) {
+// HasFeature reports whether the PDB stream records the given feature code.
+func (pdbStream *PDBStream) HasFeature(feature PDBFeature) bool {
+	for _, f := range pdbStream.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIPIStream reports whether the PDB file contains an IPI stream (stream 4),
+// as is the case for PDB files produced by VC 11.0 and later.
+func (pdbStream *PDBStream) HasIPIStream() bool {
+	return pdbStream.HasFeature(PDBFeatureVC110) || pdbStream.HasFeature(PDBFeatureVC140)
+}
+
+// IsMinimalDebugInfo reports whether the PDB file was produced with minimal
+// debug information (/DEBUG:FASTLINK), in which case type and symbol
+// information is kept in the object files rather than merged into the PDB.
+func (pdbStream *PDBStream) IsMinimalDebugInfo() bool {
+	return pdbStream.HasFeature(PDBFeatureMinimalDebugInfo)
+}
+
+// HasTypeMerging reports whether types were merged into the TPI and IPI streams
+// of the PDB file by the linker.
+func (pdbStream *PDBStream) HasTypeMerging() bool {
+	return !pdbStream.HasFeature(PDBFeatureNoTypeMerge)
+}
+
+// parsePDBStream parses the given PDB stream, reading from r.
+func (file *File) parsePDBStream(r *StreamReader) (*PDBStream, error) {
 	// Parse PDB stream header.
 	pdbStream := &PDBStream{}
 	hdr, err := file.parsePDBStreamHeader(r)
@@ -27,10 +59,65 @@ func (file *File) parsePDBStream(r io.Reader) (*PDBStream, error) {
 		return nil, errors.WithStack(err)
 	}
 	pdbStream.Hdr = hdr
-	// TODO: parse StreamNameMap.
+	// Parse named stream map; not present in PDB files of early versions.
+	if r.off < r.Size() {
+		streamNameMap, err := file.parseStreamNameMap(r)
+		if err != nil {
+			if !file.opts.Recover {
+				return nil, errors.WithStack(err)
+			}
+			file.addDiagnostic(SeverityError, r.formatError(err))
+			return pdbStream, nil
+		}
+		pdbStream.StreamNameMap = streamNameMap
+	}
+	// Parse feature codes.
+	features, err := file.parsePDBFeatures(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pdbStream.Features = features
 	return pdbStream, nil
 }
 
+//go:generate stringer -linecomment -type PDBFeature
+
+// PDBFeature specifies a feature code of the PDB stream, which changes how
+// other streams of the PDB file are interpreted.
+type PDBFeature uint32
+
+// PDB feature codes.
+//
+// ref: PdbRaw_FeatureSig in llvm/DebugInfo/PDB/Native/RawConstants.h
+const (
+	// IPI stream present (VC 11.0); no other feature codes follow.
+	PDBFeatureVC110 PDBFeature = 20091201 // VC110
+	// IPI stream present (VC 14.0).
+	PDBFeatureVC140 PDBFeature = 20140508 // VC140
+	// Types not merged into the TPI and IPI streams ("NOTM").
+	PDBFeatureNoTypeMerge PDBFeature = 0x4D544F4E // NoTypeMerge
+	// Minimal debug information (/DEBUG:FASTLINK) ("MINI").
+	PDBFeatureMinimalDebugInfo PDBFeature = 0x494E494D // MinimalDebugInfo
+)
+
+// parsePDBFeatures parses the feature codes following the named stream map of
+// the PDB stream, reading from r until the end of the stream.
+func (file *File) parsePDBFeatures(r *StreamReader) ([]PDBFeature, error) {
+	var features []PDBFeature
+	for r.Size()-r.off >= 4 {
+		var feature PDBFeature
+		if err := binary.Read(r, binary.LittleEndian, &feature); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		features = append(features, feature)
+		// No other feature codes follow VC110.
+		if feature == PDBFeatureVC110 {
+			break
+		}
+	}
+	return features, nil
+}
+
 // PDBStreamHeader is a header of the PDB stream.
 type PDBStreamHeader struct {
 	// PDB version.
@@ -39,7 +126,8 @@ type PDBStreamHeader struct {
 	Date time.Time
 	// Number of times the PDB file as been written to.
 	Age uint32
-	// Unique ID of the PDB.
+	// Unique ID of the PDB; zero for PDB versions prior to VC 7.0, which are
+	// identified by Date.
 	UniqueID GUID
 }
 
@@ -66,9 +154,6 @@ const (
 	PDBVersionVC140          PDBVersion = 20140508 // VC 14.0 (2014-05-08)
 )
 
-// GUID is a globally unique identifier.
-type GUID [2]uint64
-
 // parsePDBStreamHeader parses the given PDB stream header.
 func (file *File) parsePDBStreamHeader(r io.Reader) (*PDBStreamHeader, error) {
 	// Version.
@@ -86,16 +171,12 @@ func (file *File) parsePDBStreamHeader(r io.Reader) (*PDBStreamHeader, error) {
 	if err := binary.Read(r, binary.LittleEndian, &hdr.Age); err != nil {
 		return nil, errors.WithStack(err)
 	}
-	// UniqueID.
+	// UniqueID; not present prior to VC 7.0.
+	if hdr.Version < PDBVersionVC70Deprecated {
+		return hdr, nil
+	}
 	if err := binary.Read(r, binary.LittleEndian, &hdr.UniqueID); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	return hdr, nil
 }
-
-// StreamNameMap maps from stream name to stream number.
-//
-// ref: https://llvm.org/docs/PDB/PdbStream.html#named-stream-map
-type StreamNameMap struct {
-	// TODO: implement when we find a sample PDB that uses this.
-}
@@ -0,0 +1,170 @@
+package pdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// newTestRecoverPDB returns the contents of a PDB file whose PDB stream holds a
+// named stream map with an invalid string buffer size, and whose previous
+// stream table (stream 0) holds an invalid page number.
+func newTestRecoverPDB() []byte {
+	pdbStream := &bytes.Buffer{}
+	w := func(v interface{}) {
+		binary.Write(pdbStream, binary.LittleEndian, v)
+	}
+	w(PDBVersionVC70)
+	w(uint32(0)) // Date
+	w(uint32(1)) // Age
+	w(testCodeView.GUID)
+	w(uint32(0xFFFF)) // StringBufSize
+	// NStreams, stream size and page number map (MSF 7.00).
+	prevStreamTbl := &bytes.Buffer{}
+	binary.Write(prevStreamTbl, binary.LittleEndian, []uint32{1, 100, 1000})
+	return newTestMSF(true, prevStreamTbl.Bytes(), pdbStream.Bytes()).image()
+}
+
+func TestPDBStreamDiagnostics(t *testing.T) {
+	// The PDB stream is decoded once, both when consulted through PDBInfo and
+	// when decoded by ParseStreams; its diagnostics are recorded once.
+	buf := newTestRecoverPDB()
+	file, err := Open(bytes.NewReader(buf), int64(len(buf)), &ParseOptions{Recover: true})
+	if err != nil {
+		t.Fatalf("unable to open PDB file; %+v", err)
+	}
+	pdbStream, err := file.PDBInfo()
+	if err != nil {
+		t.Fatalf("unable to decode PDB stream; %+v", err)
+	}
+	if err := file.ParseStreams(); err != nil {
+		t.Fatalf("unable to parse streams; %+v", err)
+	}
+	if file.Streams[StreamIDPDBStream] != pdbStream {
+		t.Errorf("expected PDB stream of ParseStreams to be shared with PDBInfo")
+	}
+	n := 0
+	for _, d := range file.Diagnostics() {
+		if d.StreamNum == int(StreamIDPDBStream) {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("number of PDB stream diagnostics mismatch; expected 1, got %d", n)
+	}
+}
+
+func TestDiagnosticsConcurrent(t *testing.T) {
+	// Diagnostics recorded by streams decoded on demand may be read
+	// concurrently (run with -race).
+	buf := newTestRecoverPDB()
+	file, err := Open(bytes.NewReader(buf), int64(len(buf)), &ParseOptions{Recover: true})
+	if err != nil {
+		t.Fatalf("unable to open PDB file; %+v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			file.PrevStreamTable()
+		}()
+		go func() {
+			defer wg.Done()
+			file.PrevStreamReader(StreamNumber(StreamIDPDBStream))
+		}()
+		go func() {
+			defer wg.Done()
+			file.Diagnostics()
+		}()
+	}
+	wg.Wait()
+	// The previous stream table is decoded once, both when consulted through
+	// PrevStreamTable and PrevStreamReader; its diagnostics are recorded once.
+	n := 0
+	for _, d := range file.Diagnostics() {
+		if d.StreamNum == int(StreamIDPrevStreamTable) {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("number of previous stream table diagnostics mismatch; expected 1, got %d", n)
+	}
+}
+
+func TestStreamCache(t *testing.T) {
+	// Streams decoded on demand are decoded once, and shared between callers.
+	buf := newTestPDB(testCodeView)
+	file, err := Open(bytes.NewReader(buf), int64(len(buf)), nil)
+	if err != nil {
+		t.Fatalf("unable to open PDB file; %+v", err)
+	}
+	var (
+		wg      sync.WaitGroup
+		streams [4]*PDBStream
+	)
+	for i := range streams {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pdbStream, err := file.PDBInfo()
+			if err != nil {
+				t.Errorf("unable to decode PDB stream; %+v", err)
+				return
+			}
+			streams[i] = pdbStream
+		}(i)
+	}
+	wg.Wait()
+	for i, pdbStream := range streams {
+		if pdbStream != streams[0] {
+			t.Errorf("PDB stream %d differs from PDB stream 0; expected cached stream", i)
+		}
+	}
+}
+
+// newTestNamedPDB returns the contents of a PDB file matching the given
+// CodeView debug information, holding the given named streams in order of
+// name, starting at stream 5 (following the fixed streams, which are nil).
+func newTestNamedPDB(cv *CodeViewInfo, named map[string][]byte) []byte {
+	var names []string
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pdbStream := &bytes.Buffer{}
+	w := func(v interface{}) {
+		binary.Write(pdbStream, binary.LittleEndian, v)
+	}
+	w(PDBVersionVC70)
+	w(uint32(0)) // Date
+	w(cv.Age)
+	w(cv.GUID)
+	// Named stream map; string buffer, followed by hash table with one bucket
+	// per named stream.
+	strBuf := &bytes.Buffer{}
+	var offsets []uint32
+	for _, name := range names {
+		offsets = append(offsets, uint32(strBuf.Len()))
+		strBuf.WriteString(name)
+		strBuf.WriteByte(0)
+	}
+	w(uint32(strBuf.Len()))
+	pdbStream.Write(strBuf.Bytes())
+	n := uint32(len(names))
+	w(n)                // Size
+	w(n)                // Capacity
+	w(uint32(1))        // Present; number of words.
+	w(uint32(1)<<n - 1) // Present; words.
+	w(uint32(0))        // Deleted; number of words.
+	streams := [][]byte{nil, nil, nil, nil, nil}
+	for i, name := range names {
+		w(offsets[i])
+		w(uint32(len(streams)))
+		streams = append(streams, named[name])
+	}
+	streams[StreamIDPDBStream] = pdbStream.Bytes()
+	return newTestMSF(true, streams...).image()
+}
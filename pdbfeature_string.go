@@ -0,0 +1,37 @@
+// Code generated by "stringer -linecomment -type PDBFeature"; DO NOT EDIT.
+
+package pdb
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[PDBFeatureVC110-20091201]
+	_ = x[PDBFeatureVC140-20140508]
+	_ = x[PDBFeatureNoTypeMerge-1297370958]
+	_ = x[PDBFeatureMinimalDebugInfo-1229867341]
+}
+
+const (
+	_PDBFeature_name_0 = "VC110"
+	_PDBFeature_name_1 = "VC140"
+	_PDBFeature_name_2 = "MinimalDebugInfo"
+	_PDBFeature_name_3 = "NoTypeMerge"
+)
+
+func (i PDBFeature) String() string {
+	switch {
+	case i == 20091201:
+		return _PDBFeature_name_0
+	case i == 20140508:
+		return _PDBFeature_name_1
+	case i == 1229867341:
+		return _PDBFeature_name_2
+	case i == 1297370958:
+		return _PDBFeature_name_3
+	default:
+		return "PDBFeature(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+}
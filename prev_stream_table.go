@@ -0,0 +1,147 @@
+package pdb
+
+import "github.com/pkg/errors"
+
+// ErrNoPrevStreamTable is returned by File.PrevStreamTable if the PDB file has
+// no previous stream table (i.e. stream 0 is nil or empty).
+var ErrNoPrevStreamTable = errors.New("no previous stream table")
+
+// PrevStreamTable returns the previous stream table of the PDB file, as stored
+// in stream 0 (the old MSF stream table).
+//
+// The previous stream table is the stream table of the last committed state
+// of the MSF, and may be used to recover data from a PDB file whose last
+// commit (e.g. of an incremental link) was interrupted.
+//
+// The previous stream table is decoded once, on first use, and shared between
+// callers.
+func (file *File) PrevStreamTable() (*StreamTable, error) {
+	file.prevStreamTblOnce.Do(func() {
+		file.prevStreamTbl, file.prevStreamTblErr = file.parsePrevStreamTable()
+	})
+	return file.prevStreamTbl, file.prevStreamTblErr
+}
+
+// parsePrevStreamTable parses the previous stream table of the PDB file, as
+// stored in stream 0.
+func (file *File) parsePrevStreamTable() (*StreamTable, error) {
+	if len(file.StreamTbl.StreamInfos) == 0 {
+		return nil, ErrNoPrevStreamTable
+	}
+	streamInfo := file.StreamTbl.StreamInfos[StreamIDPrevStreamTable]
+	if streamInfo.IsNil() || streamInfo.Size == 0 {
+		return nil, ErrNoPrevStreamTable
+	}
+	sr, err := file.StreamReader(StreamNumber(StreamIDPrevStreamTable))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	prevStreamTbl, err := file.parseStreamTable(sr)
+	if err != nil {
+		return nil, errors.WithStack(sr.formatError(err))
+	}
+	return prevStreamTbl, nil
+}
+
+// PrevStreamReader returns a reader of the contents of the stream with the
+// given stream number, as located by the previous stream table of the PDB
+// file.
+func (file *File) PrevStreamReader(streamNum StreamNumber) (*StreamReader, error) {
+	prevStreamTbl, err := file.PrevStreamTable()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sr, err := file.streamReader(prevStreamTbl, streamNum)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sr.name += " (previous stream table)"
+	return sr, nil
+}
+
+// StreamDiff records the difference of a stream between two stream tables.
+type StreamDiff struct {
+	// Stream number.
+	StreamNum StreamNumber
+	// Kind of difference.
+	Kind StreamDiffKind
+	// Stream information in old stream table; nil stream if not present.
+	Old StreamInfo
+	// Stream information in new stream table; nil stream if not present.
+	New StreamInfo
+}
+
+//go:generate stringer -linecomment -type StreamDiffKind
+
+// StreamDiffKind specifies the kind of difference of a stream between two
+// stream tables.
+type StreamDiffKind uint8
+
+// Stream difference kinds.
+const (
+	// Stream present in new but not in old stream table.
+	StreamDiffAdded StreamDiffKind = iota + 1 // added
+	// Stream present in old but not in new stream table.
+	StreamDiffRemoved // removed
+	// Stream size differs.
+	StreamDiffResized // resized
+	// Stream size is the same, but the stream is located in different pages.
+	StreamDiffMoved // moved
+)
+
+// DiffStreamTables returns the differences between the streams of the old and
+// the new stream table, in increasing order of stream number. Streams which are
+// nil in both tables, or located in the same pages with the same size, are
+// omitted.
+func DiffStreamTables(old, new *StreamTable) []StreamDiff {
+	nilStream := StreamInfo{Size: nilStreamSize}
+	n := len(old.StreamInfos)
+	if len(new.StreamInfos) > n {
+		n = len(new.StreamInfos)
+	}
+	var diffs []StreamDiff
+	for i := 0; i < n; i++ {
+		oldInfo, newInfo := nilStream, nilStream
+		var oldPageNumMap, newPageNumMap []uint32
+		if i < len(old.StreamInfos) {
+			oldInfo, oldPageNumMap = old.StreamInfos[i], old.PageNumMaps[i]
+		}
+		if i < len(new.StreamInfos) {
+			newInfo, newPageNumMap = new.StreamInfos[i], new.PageNumMaps[i]
+		}
+		diff := StreamDiff{
+			StreamNum: StreamNumber(i),
+			Old:       oldInfo,
+			New:       newInfo,
+		}
+		switch {
+		case oldInfo.IsNil() && newInfo.IsNil():
+			continue
+		case oldInfo.IsNil():
+			diff.Kind = StreamDiffAdded
+		case newInfo.IsNil():
+			diff.Kind = StreamDiffRemoved
+		case oldInfo.Size != newInfo.Size:
+			diff.Kind = StreamDiffResized
+		case !equalPageNumMaps(oldPageNumMap, newPageNumMap):
+			diff.Kind = StreamDiffMoved
+		default:
+			continue
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// equalPageNumMaps reports whether the given page number maps are equal.
+func equalPageNumMaps(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
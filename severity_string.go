@@ -0,0 +1,26 @@
+// Code generated by "stringer -linecomment -type Severity"; DO NOT EDIT.
+
+package pdb
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SeverityInfo-1]
+	_ = x[SeverityWarning-2]
+	_ = x[SeverityError-3]
+}
+
+const _Severity_name = "infowarningerror"
+
+var _Severity_index = [...]uint8{0, 4, 11, 16}
+
+func (i Severity) String() string {
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_Severity_index)-1 {
+		return "Severity(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Severity_name[_Severity_index[idx]:_Severity_index[idx+1]]
+}
@@ -0,0 +1,120 @@
+package pdb
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SourceLink maps local source file paths to URLs from which the exact
+// revision of the source files may be fetched, as stored in the "sourcelink"
+// stream.
+//
+// Example:
+//
+//    {
+//       "documents": {
+//          "C:\\src\\*": "https://raw.githubusercontent.com/org/repo/commit/*"
+//       }
+//    }
+//
+// ref: https://github.com/dotnet/designs/blob/main/accepted/2020/diagnostics/source-link.md
+type SourceLink struct {
+	streamMeta
+	// Documents mapping from local path to URL, sorted by path.
+	Documents []SourceLinkDocument
+}
+
+// SourceLinkStreamName is the name of the Source Link stream, as located by the
+// named stream map.
+const SourceLinkStreamName = "sourcelink"
+
+// SourceLink returns the Source Link mappings of the PDB file, as stored in the
+// "sourcelink" stream, decoding it if not already present in file.Streams.
+func (file *File) SourceLink() (*SourceLink, error) {
+	stream, err := file.StreamByName(SourceLinkStreamName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sourceLink, ok := stream.(*SourceLink)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %q stream", SourceLinkStreamName)
+	}
+	return sourceLink, nil
+}
+
+// SourceLinkDocument maps a local path to a URL. A path ending with "*"
+// matches all files with the given path prefix, and the "*" of the URL is
+// replaced by the remainder of the file path, using forward slashes.
+type SourceLinkDocument struct {
+	// Local path, optionally ending with a "*" wildcard.
+	Path string
+	// URL, containing a "*" wildcard if the local path does.
+	URL string
+}
+
+// Resolve returns the URL of the given source file path, and a boolean
+// indicating whether the path was matched by a document. Paths are matched
+// case-insensitively; if several documents match, the one with the longest
+// path is used.
+func (sourceLink *SourceLink) Resolve(path string) (string, bool) {
+	var (
+		match *SourceLinkDocument
+		rest  string
+	)
+	for i := range sourceLink.Documents {
+		doc := &sourceLink.Documents[i]
+		if match != nil && len(doc.Path) <= len(match.Path) {
+			continue
+		}
+		if prefix := strings.TrimSuffix(doc.Path, "*"); len(prefix) < len(doc.Path) {
+			if len(path) >= len(prefix) && strings.EqualFold(path[:len(prefix)], prefix) {
+				match, rest = doc, path[len(prefix):]
+			}
+			continue
+		}
+		if strings.EqualFold(path, doc.Path) {
+			match, rest = doc, ""
+		}
+	}
+	if match == nil {
+		return "", false
+	}
+	rest = strings.ReplaceAll(rest, `\`, "/")
+	return strings.Replace(match.URL, "*", rest, 1), true
+}
+
+// parseSourceLink parses the given Source Link stream, reading from r.
+func (file *File) parseSourceLink(r *StreamReader) (*SourceLink, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var data struct {
+		Documents map[string]string `json:"documents"`
+	}
+	if err := json.Unmarshal(buf, &data); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sourceLink := &SourceLink{}
+	for path, url := range data.Documents {
+		if strings.Count(path, "*") > 1 || (strings.Contains(path, "*") && !strings.HasSuffix(path, "*")) {
+			return nil, errors.Errorf("invalid Source Link path %q; expected at most one trailing wildcard", path)
+		}
+		if strings.HasSuffix(path, "*") != strings.Contains(url, "*") {
+			return nil, errors.Errorf("mismatch between wildcards of Source Link path %q and URL %q", path, url)
+		}
+		doc := SourceLinkDocument{
+			Path: path,
+			URL:  url,
+		}
+		sourceLink.Documents = append(sourceLink.Documents, doc)
+	}
+	sort.Slice(sourceLink.Documents, func(i, j int) bool {
+		return sourceLink.Documents[i].Path < sourceLink.Documents[j].Path
+	})
+	return sourceLink, nil
+}
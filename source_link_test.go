@@ -0,0 +1,122 @@
+package pdb
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSourceLinkResolve(t *testing.T) {
+	sourceLink := &SourceLink{
+		Documents: []SourceLinkDocument{
+			{Path: `C:\src\*`, URL: "https://example.org/repo/*"},
+			{Path: `C:\src\lib\*`, URL: "https://example.org/lib/*"},
+			{Path: `C:\src\lib\gen.c`, URL: "https://example.org/gen/gen.c"},
+			{Path: `D:\other.c`, URL: "https://example.org/other.c"},
+		},
+	}
+	golden := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		// Wildcard; remainder of path using forward slashes.
+		{path: `C:\src\main.c`, want: "https://example.org/repo/main.c", wantOK: true},
+		{path: `C:\src\a\b\c.c`, want: "https://example.org/repo/a/b/c.c", wantOK: true},
+		// Longest path wins.
+		{path: `C:\src\lib\x.c`, want: "https://example.org/lib/x.c", wantOK: true},
+		{path: `C:\src\lib\gen.c`, want: "https://example.org/gen/gen.c", wantOK: true},
+		// Case-insensitive matching; case of remainder is preserved.
+		{path: `c:\SRC\Main.c`, want: "https://example.org/repo/Main.c", wantOK: true},
+		{path: `d:\OTHER.C`, want: "https://example.org/other.c", wantOK: true},
+		// Exact path without wildcard does not match prefixes.
+		{path: `D:\other.c.bak`, wantOK: false},
+		{path: `C:\src`, wantOK: false},
+		{path: `E:\main.c`, wantOK: false},
+	}
+	for _, g := range golden {
+		got, ok := sourceLink.Resolve(g.path)
+		if ok != g.wantOK || got != g.want {
+			t.Errorf("%q: URL mismatch; expected (%q, %v), got (%q, %v)", g.path, g.want, g.wantOK, got, ok)
+		}
+	}
+}
+
+func TestParseSourceLinkMalformed(t *testing.T) {
+	golden := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid JSON", data: `{"documents": `},
+		{name: "multiple wildcards", data: `{"documents": {"C:\\*\\*": "https://example.org/*"}}`},
+		{name: "non-trailing wildcard", data: `{"documents": {"C:\\*\\a.c": "https://example.org/*"}}`},
+		{name: "missing URL wildcard", data: `{"documents": {"C:\\src\\*": "https://example.org/"}}`},
+		{name: "missing path wildcard", data: `{"documents": {"C:\\src\\a.c": "https://example.org/*"}}`},
+	}
+	for _, g := range golden {
+		buf := newTestNamedPDB(testCodeView, map[string][]byte{SourceLinkStreamName: []byte(g.data)})
+		file := openTestPDB(t, buf)
+		if _, err := file.SourceLink(); err == nil {
+			t.Errorf("%s: expected error, got nil", g.name)
+		}
+	}
+}
+
+func TestResolveSource(t *testing.T) {
+	// Source Link takes precedence over the source server stream; the source
+	// server stream is consulted for source files not matched by Source Link.
+	sourceLinkData := `{"documents": {"c:\\src\\lib\\*": "https://example.org/sourcelink/*"}}`
+	buf := newTestNamedPDB(testCodeView, map[string][]byte{
+		SourceLinkStreamName: []byte(sourceLinkData),
+		SrcSrvStreamName:     []byte(testSrcSrv),
+	})
+	file := openTestPDB(t, buf)
+	golden := []struct {
+		srcPath string
+		want    *SourceLocation
+	}{
+		{
+			srcPath: `c:\src\lib\util.c`,
+			want: &SourceLocation{
+				StreamName: SourceLinkStreamName,
+				URL:        "https://example.org/sourcelink/util.c",
+				Target:     "https://example.org/sourcelink/util.c",
+			},
+		},
+		{
+			srcPath: `c:\src\main.c`,
+			want: &SourceLocation{
+				StreamName: SrcSrvStreamName,
+				URL:        "https://example.org/repo/src/main.c",
+				Target:     "https://example.org/repo/src/main.c",
+			},
+		},
+	}
+	for _, g := range golden {
+		got, err := file.ResolveSource(g.srcPath)
+		if err != nil {
+			t.Errorf("%q: unable to resolve source file; %+v", g.srcPath, err)
+			continue
+		}
+		if *got != *g.want {
+			t.Errorf("%q: source location mismatch; expected %+v, got %+v", g.srcPath, g.want, got)
+		}
+	}
+	// Source file indexed by neither stream.
+	if _, err := file.ResolveSource(`c:\src\missing.c`); errors.Cause(err) != ErrSourceNotFound {
+		t.Errorf("expected ErrSourceNotFound, got %v", err)
+	}
+	// Source file indexed by both streams.
+	both := newTestNamedPDB(testCodeView, map[string][]byte{
+		SourceLinkStreamName: []byte(`{"documents": {"c:\\src\\*": "https://example.org/sourcelink/*"}}`),
+		SrcSrvStreamName:     []byte(testSrcSrv),
+	})
+	file = openTestPDB(t, both)
+	got, err := file.ResolveSource(`c:\src\main.c`)
+	if err != nil {
+		t.Fatalf("unable to resolve source file; %+v", err)
+	}
+	if got.StreamName != SourceLinkStreamName {
+		t.Errorf("stream name mismatch; expected %q, got %q", SourceLinkStreamName, got.StreamName)
+	}
+}
@@ -0,0 +1,87 @@
+package pdb
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrSourceNotFound is returned by File.ResolveSource when the source file is
+// not indexed by the PDB file.
+var ErrSourceNotFound = errors.New("source file not indexed by PDB file")
+
+// SourceLocation specifies where to fetch the exact revision of a source file,
+// as indexed by the Source Link or source server stream of the PDB file.
+type SourceLocation struct {
+	// Stream which indexed the source file (SourceLinkStreamName or
+	// SrcSrvStreamName).
+	StreamName string
+	// URL of the source file; or empty if the source file is not fetched over
+	// HTTP.
+	URL string
+	// Target of the source file (expanded SRCSRVTRG variable of the source
+	// server stream); same as URL for Source Link.
+	Target string
+	// Command which extracts the source file (expanded SRCSRVCMD variable of
+	// the source server stream); or empty if not specified.
+	Command string
+}
+
+// ResolveSource returns the location of the exact revision of the given source
+// file, as indexed by the Source Link stream ("sourcelink") or the source
+// server stream ("srcsrv") of the PDB file; Source Link takes precedence.
+// ErrSourceNotFound is returned if the source file is not indexed by either
+// stream.
+func (file *File) ResolveSource(srcPath string) (*SourceLocation, error) {
+	// Source Link.
+	if file.hasNamedStream(SourceLinkStreamName) {
+		sourceLink, err := file.SourceLink()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if url, ok := sourceLink.Resolve(srcPath); ok {
+			loc := &SourceLocation{
+				StreamName: SourceLinkStreamName,
+				URL:        url,
+				Target:     url,
+			}
+			return loc, nil
+		}
+	}
+	// Source server.
+	if file.hasNamedStream(SrcSrvStreamName) {
+		srcSrv, err := file.SrcSrv()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if srcFile, ok := srcSrv.Lookup(srcPath); ok {
+			target, err := srcSrv.Target(srcFile)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			cmd, err := srcSrv.Command(srcFile)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			loc := &SourceLocation{
+				StreamName: SrcSrvStreamName,
+				Target:     target,
+				Command:    cmd,
+			}
+			if isURL(target) {
+				loc.URL = target
+			}
+			return loc, nil
+		}
+	}
+	return nil, errors.Wrapf(ErrSourceNotFound, "unable to resolve source file %q", srcPath)
+}
+
+// hasNamedStream reports whether the PDB file contains a stream of the given
+// name.
+func (file *File) hasNamedStream(name string) bool {
+	streamNameMap, err := file.streamNameMap()
+	if err != nil {
+		return false
+	}
+	_, ok := streamNameMap.Lookup(name)
+	return ok
+}
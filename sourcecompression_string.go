@@ -0,0 +1,36 @@
+// Code generated by "stringer -linecomment -type SourceCompression"; DO NOT EDIT.
+
+package pdb
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[SourceCompressionNone-0]
+	_ = x[SourceCompressionRLE-1]
+	_ = x[SourceCompressionHuffman-2]
+	_ = x[SourceCompressionLZ-3]
+	_ = x[SourceCompressionDotNet-101]
+}
+
+const (
+	_SourceCompression_name_0 = "nonerun-length encodedHuffmanLZ"
+	_SourceCompression_name_1 = ".NET"
+)
+
+var (
+	_SourceCompression_index_0 = [...]uint8{0, 4, 22, 29, 31}
+)
+
+func (i SourceCompression) String() string {
+	switch {
+	case i <= 3:
+		return _SourceCompression_name_0[_SourceCompression_index_0[i]:_SourceCompression_index_0[i+1]]
+	case i == 101:
+		return _SourceCompression_name_1
+	default:
+		return "SourceCompression(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+}
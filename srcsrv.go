@@ -0,0 +1,311 @@
+package pdb
+
+import (
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SrcSrv is the source server data of the PDB file, as stored in the "srcsrv"
+// stream. The source server data specifies for each indexed source file how to
+// fetch the exact revision of the source file from version control, using
+// variables which are expanded per source file.
+//
+// Example:
+//
+//    SRCSRV: ini ------------------------------------------------
+//    VERSION=2
+//    VERCTRL=http
+//    SRCSRV: variables ------------------------------------------
+//    SRCSRVTRG=https://example.org/%var2%/%var3%
+//    SRCSRV: source files ---------------------------------------
+//    c:\src\main.c*repo*main.c
+//    SRCSRV: end ------------------------------------------------
+//
+// ref: https://docs.microsoft.com/en-us/windows-hardware/drivers/debugger/language-specification-1
+type SrcSrv struct {
+	streamMeta
+	// Variables of the ini section (e.g. VERSION, VERCTRL), keyed by upper-case
+	// name.
+	Ini map[string]string
+	// Variables of the variables section (e.g. SRCSRVTRG, SRCSRVCMD), keyed by
+	// upper-case name.
+	Vars map[string]string
+	// Source files.
+	Files []SrcSrvFile
+}
+
+// SrcSrvStreamName is the name of the source server stream, as located by the
+// named stream map.
+const SrcSrvStreamName = "srcsrv"
+
+// SrcSrv returns the source server data of the PDB file, as stored in the
+// "srcsrv" stream, decoding it if not already present in file.Streams.
+func (file *File) SrcSrv() (*SrcSrv, error) {
+	stream, err := file.StreamByName(SrcSrvStreamName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	srcSrv, ok := stream.(*SrcSrv)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %q stream", SrcSrvStreamName)
+	}
+	return srcSrv, nil
+}
+
+// SrcSrvFile is a source file entry of the source server data.
+type SrcSrvFile struct {
+	// Fields of the source file entry, referred to as %var1% through %var10%;
+	// the first field is the local path of the source file.
+	Vars []string
+}
+
+// Path returns the local path of the source file.
+func (srcFile *SrcSrvFile) Path() string {
+	return srcFile.Vars[0]
+}
+
+// Lookup returns the source file entry of the given local path, and a boolean
+// indicating whether the source file was found. Paths are matched
+// case-insensitively.
+func (srcSrv *SrcSrv) Lookup(srcPath string) (*SrcSrvFile, bool) {
+	for i := range srcSrv.Files {
+		srcFile := &srcSrv.Files[i]
+		if strings.EqualFold(srcFile.Path(), srcPath) {
+			return srcFile, true
+		}
+	}
+	return nil, false
+}
+
+// Target returns the target of the given source file (i.e. the expanded
+// SRCSRVTRG variable); the location of the extracted source file, often a
+// URL.
+func (srcSrv *SrcSrv) Target(srcFile *SrcSrvFile) (string, error) {
+	target, err := srcSrv.Expand(srcFile, "%SRCSRVTRG%")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return target, nil
+}
+
+// Command returns the command which extracts the given source file (i.e. the
+// expanded SRCSRVCMD variable); or an empty string if not specified.
+func (srcSrv *SrcSrv) Command(srcFile *SrcSrvFile) (string, error) {
+	if _, ok := srcSrv.Vars["SRCSRVCMD"]; !ok {
+		return "", nil
+	}
+	cmd, err := srcSrv.Expand(srcFile, "%SRCSRVCMD%")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return cmd, nil
+}
+
+// maxSrcSrvExpandDepth is the maximum nesting depth of variable expansion;
+// used to detect recursive variable definitions.
+const maxSrcSrvExpandDepth = 32
+
+// maxSrcSrvExpandSize is the maximum size in bytes of expanded text; used to
+// detect variable definitions which expand exponentially (e.g. A=%B%%B%,
+// B=%C%%C%, ...). The expansion of each variable is memoized, so that the
+// time spent expanding such definitions is bounded as well.
+const maxSrcSrvExpandSize = 64 * 1024
+
+// Expand expands the variables of the given text for the given source file.
+// Variable names are case-insensitive; %var1% through %var10% refer to the
+// fields of the source file entry, and other variables are defined by the
+// variables and ini sections. The functions %fnvar%(name), %fnbksl%(path) and
+// %fnfile%(path) are supported. Undefined variables (e.g. %targ%, the local
+// extraction directory of the debugger) are left unexpanded. An error is
+// returned if the expanded text exceeds 64 KiB.
+func (srcSrv *SrcSrv) Expand(srcFile *SrcSrvFile, text string) (string, error) {
+	memo := make(map[string]string)
+	s, err := srcSrv.expand(srcFile, text, 0, memo)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return s, nil
+}
+
+// expand expands the variables of the given text for the given source file, at
+// the given nesting depth. memo maps from upper-case variable name to the
+// expanded value of variables already expanded.
+func (srcSrv *SrcSrv) expand(srcFile *SrcSrvFile, text string, depth int, memo map[string]string) (string, error) {
+	if depth > maxSrcSrvExpandDepth {
+		return "", errors.Errorf("maximum expansion depth (%d) exceeded; recursive variable definition in %q", maxSrcSrvExpandDepth, text)
+	}
+	buf := &strings.Builder{}
+	for len(text) > 0 {
+		if buf.Len() > maxSrcSrvExpandSize {
+			return "", errors.Errorf("maximum expansion size (%d bytes) exceeded", maxSrcSrvExpandSize)
+		}
+		start := strings.IndexByte(text, '%')
+		if start == -1 {
+			buf.WriteString(text)
+			break
+		}
+		end := strings.IndexByte(text[start+1:], '%')
+		if end == -1 {
+			buf.WriteString(text)
+			break
+		}
+		end += start + 1
+		buf.WriteString(text[:start])
+		name := strings.ToUpper(text[start+1 : end])
+		// Closing '%' of undefined variables may open the next variable.
+		raw, next := text[start:end], text[end:]
+		text = text[end+1:]
+		// Functions.
+		switch name {
+		case "FNVAR", "FNBKSL", "FNFILE":
+			arg, rest, ok := srcSrvFuncArg(text)
+			if !ok {
+				return "", errors.Errorf("invalid argument of function %%%s%%; missing parentheses", strings.ToLower(name))
+			}
+			text = rest
+			s, err := srcSrv.expand(srcFile, arg, depth+1, memo)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			switch name {
+			case "FNVAR":
+				s, err = srcSrv.expand(srcFile, "%"+s+"%", depth+1, memo)
+				if err != nil {
+					return "", errors.WithStack(err)
+				}
+			case "FNBKSL":
+				s = strings.ReplaceAll(s, "/", `\`)
+			case "FNFILE":
+				s = path.Base(strings.ReplaceAll(s, `\`, "/"))
+			}
+			buf.WriteString(s)
+			continue
+		}
+		// Fields of source file entry.
+		if strings.HasPrefix(name, "VAR") {
+			if n, err := strconv.Atoi(name[len("VAR"):]); err == nil && n >= 1 && n <= 10 {
+				if n <= len(srcFile.Vars) {
+					buf.WriteString(srcFile.Vars[n-1])
+				}
+				continue
+			}
+		}
+		// Variables.
+		if s, ok := memo[name]; ok {
+			buf.WriteString(s)
+			continue
+		}
+		val, ok := srcSrv.Vars[name]
+		if !ok {
+			val, ok = srcSrv.Ini[name]
+		}
+		if !ok {
+			// Leave undefined variables unexpanded.
+			buf.WriteString(raw)
+			text = next
+			continue
+		}
+		s, err := srcSrv.expand(srcFile, val, depth+1, memo)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		memo[name] = s
+		buf.WriteString(s)
+	}
+	if buf.Len() > maxSrcSrvExpandSize {
+		return "", errors.Errorf("maximum expansion size (%d bytes) exceeded", maxSrcSrvExpandSize)
+	}
+	return buf.String(), nil
+}
+
+// srcSrvFuncArg returns the parenthesized argument of a function at the start
+// of the given text, the remaining text following the argument, and a boolean
+// indicating success.
+func srcSrvFuncArg(text string) (arg, rest string, ok bool) {
+	if !strings.HasPrefix(text, "(") {
+		return "", "", false
+	}
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return text[1:i], text[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// SrcSrv sections.
+const (
+	srcSrvSectionIni       = "ini"
+	srcSrvSectionVariables = "variables"
+	srcSrvSectionFiles     = "source files"
+	srcSrvSectionEnd       = "end"
+)
+
+// parseSrcSrv parses the given source server stream, reading from r.
+func (file *File) parseSrcSrv(r *StreamReader) (*SrcSrv, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	srcSrv := &SrcSrv{
+		Ini:  make(map[string]string),
+		Vars: make(map[string]string),
+	}
+	section := ""
+	lines := strings.Split(string(buf), "\n")
+	for i, line := range lines {
+		lineNum := i + 1
+		line = strings.TrimRight(line, "\r")
+		if len(line) == 0 {
+			continue
+		}
+		// Section header; e.g. "SRCSRV: ini -----".
+		if strings.HasPrefix(strings.ToUpper(line), "SRCSRV:") {
+			section = strings.ToLower(strings.TrimSpace(strings.TrimRight(line[len("SRCSRV:"):], "-")))
+			switch section {
+			case srcSrvSectionIni, srcSrvSectionVariables, srcSrvSectionFiles, srcSrvSectionEnd:
+				// valid section.
+			default:
+				return nil, errors.Errorf("invalid section %q at line %d", section, lineNum)
+			}
+			if section == srcSrvSectionEnd {
+				break
+			}
+			continue
+		}
+		switch section {
+		case srcSrvSectionIni, srcSrvSectionVariables:
+			pos := strings.IndexByte(line, '=')
+			if pos == -1 {
+				return nil, errors.Errorf("invalid variable definition %q at line %d; missing '='", line, lineNum)
+			}
+			name := strings.ToUpper(strings.TrimSpace(line[:pos]))
+			val := line[pos+1:]
+			if section == srcSrvSectionIni {
+				srcSrv.Ini[name] = val
+			} else {
+				srcSrv.Vars[name] = val
+			}
+		case srcSrvSectionFiles:
+			srcFile := SrcSrvFile{Vars: strings.Split(line, "*")}
+			srcSrv.Files = append(srcSrv.Files, srcFile)
+		default:
+			return nil, errors.Errorf("invalid line %q at line %d; expected section header", line, lineNum)
+		}
+	}
+	if section != srcSrvSectionEnd {
+		return nil, errors.New("missing end section")
+	}
+	return srcSrv, nil
+}
@@ -0,0 +1,183 @@
+package pdb
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSrcSrv is the contents of a source server stream.
+const testSrcSrv = "SRCSRV: ini ------------------------------------------------\r\n" +
+	"VERSION=2\r\n" +
+	"VERCTRL=http\r\n" +
+	"srcsrv: variables ------------------------------------------\r\n" +
+	"HTTP_ALIAS=https://example.org\r\n" +
+	"HTTP_EXTRACT_TARGET=%HTTP_ALIAS%/%var2%/%var3%\r\n" +
+	"SRCSRVTRG=%http_extract_target%\r\n" +
+	"SRCSRV: source files ---------------------------------------\r\n" +
+	"c:\\src\\main.c*repo*src/main.c\r\n" +
+	"c:\\src\\util.c*repo*src/util.c*4*5*6*7*8*9*10\r\n" +
+	"SRCSRV: end ------------------------------------------------\r\n"
+
+// openTestPDB opens the given PDB file contents.
+func openTestPDB(t *testing.T, buf []byte) *File {
+	file, err := Open(bytes.NewReader(buf), int64(len(buf)), nil)
+	if err != nil {
+		t.Fatalf("unable to open PDB file; %+v", err)
+	}
+	return file
+}
+
+func TestParseSrcSrv(t *testing.T) {
+	buf := newTestNamedPDB(testCodeView, map[string][]byte{SrcSrvStreamName: []byte(testSrcSrv)})
+	file := openTestPDB(t, buf)
+	srcSrv, err := file.SrcSrv()
+	if err != nil {
+		t.Fatalf("unable to decode source server stream; %+v", err)
+	}
+	if got, want := srcSrv.Ini["VERCTRL"], "http"; got != want {
+		t.Errorf("VERCTRL mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := srcSrv.Vars["HTTP_ALIAS"], "https://example.org"; got != want {
+		t.Errorf("HTTP_ALIAS mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := len(srcSrv.Files), 2; got != want {
+		t.Fatalf("number of source files mismatch; expected %d, got %d", want, got)
+	}
+	srcFile, ok := srcSrv.Lookup(`C:\SRC\MAIN.C`)
+	if !ok {
+		t.Fatalf("unable to locate source file %q", `C:\SRC\MAIN.C`)
+	}
+	target, err := srcSrv.Target(srcFile)
+	if err != nil {
+		t.Fatalf("unable to expand target; %+v", err)
+	}
+	if want := "https://example.org/repo/src/main.c"; target != want {
+		t.Errorf("target mismatch; expected %q, got %q", want, target)
+	}
+	// SRCSRVCMD not specified.
+	if cmd, err := srcSrv.Command(srcFile); err != nil || cmd != "" {
+		t.Errorf("command mismatch; expected (\"\", nil), got (%q, %v)", cmd, err)
+	}
+}
+
+func TestParseSrcSrvMalformed(t *testing.T) {
+	golden := []struct {
+		name string
+		data string
+	}{
+		{name: "missing end section", data: "SRCSRV: ini ---\r\nVERSION=2\r\n"},
+		{name: "invalid section", data: "SRCSRV: foo ---\r\nSRCSRV: end ---\r\n"},
+		{name: "missing '='", data: "SRCSRV: variables ---\r\nFOO\r\nSRCSRV: end ---\r\n"},
+		{name: "line outside section", data: "foo\r\nSRCSRV: end ---\r\n"},
+	}
+	for _, g := range golden {
+		buf := newTestNamedPDB(testCodeView, map[string][]byte{SrcSrvStreamName: []byte(g.data)})
+		file := openTestPDB(t, buf)
+		if _, err := file.SrcSrv(); err == nil {
+			t.Errorf("%s: expected error, got nil", g.name)
+		}
+	}
+}
+
+func TestSrcSrvExpand(t *testing.T) {
+	srcSrv := &SrcSrv{
+		Ini: map[string]string{
+			"VERCTRL": "http",
+		},
+		Vars: map[string]string{
+			"ALIAS":   "https://example.org",
+			"TARGET":  "%alias%/%var2%",
+			"NAME":    "ALIAS",
+			"PATH":    "a/b/c.c",
+			"REC":     "x%rec%",
+			"REC_A":   "%rec_b%",
+			"REC_B":   "%REC_A%",
+			"EMPTY":   "",
+			"PERCENT": "100%",
+		},
+	}
+	srcFile := &SrcSrvFile{Vars: []string{"v1", "v2", "v3", "v4", "v5", "v6", "v7", "v8", "v9", "v10"}}
+	short := &SrcSrvFile{Vars: []string{`c:\src\main.c`, "repo"}}
+	golden := []struct {
+		srcFile *SrcSrvFile
+		text    string
+		want    string
+		wantErr bool
+	}{
+		// Fields of source file entry.
+		{srcFile: srcFile, text: "%var1%", want: "v1"},
+		{srcFile: srcFile, text: "%var2%%var3%", want: "v2v3"},
+		{srcFile: srcFile, text: "%VAR4%-%Var5%-%var6%-%var7%-%var8%-%var9%", want: "v4-v5-v6-v7-v8-v9"},
+		{srcFile: srcFile, text: "%var10%", want: "v10"},
+		// Fields beyond the source file entry expand to empty strings.
+		{srcFile: short, text: "[%var3%]", want: "[]"},
+		// Not a field; %var11% and %var0% are undefined variables.
+		{srcFile: srcFile, text: "%var11%%var0%", want: "%var11%%var0%"},
+		// Variables of the variables and ini sections, case-insensitive.
+		{srcFile: srcFile, text: "%Target%", want: "https://example.org/v2"},
+		{srcFile: srcFile, text: "%verctrl%", want: "http"},
+		{srcFile: srcFile, text: "[%empty%]", want: "[]"},
+		{srcFile: srcFile, text: "%percent%", want: "100%"},
+		// Functions.
+		{srcFile: srcFile, text: "%fnvar%(%name%)", want: "https://example.org"},
+		{srcFile: srcFile, text: "%FNVAR%(NAME)", want: "ALIAS"},
+		{srcFile: srcFile, text: "%fnbksl%(%path%)", want: `a\b\c.c`},
+		{srcFile: short, text: "%fnfile%(%var1%)", want: "main.c"},
+		{srcFile: srcFile, text: "%fnfile%(%path%)", want: "c.c"},
+		{srcFile: srcFile, text: "%fnfile%", wantErr: true},
+		{srcFile: srcFile, text: "%fnbksl%(a/b", wantErr: true},
+		// Undefined variables are left unexpanded.
+		{srcFile: srcFile, text: "%targ%\\%var2%", want: `%targ%\v2`},
+		{srcFile: srcFile, text: "100% %alias%", want: "100% https://example.org"},
+		{srcFile: srcFile, text: "%unterminated", want: "%unterminated"},
+		// Recursive definitions.
+		{srcFile: srcFile, text: "%rec%", wantErr: true},
+		{srcFile: srcFile, text: "%rec_a%", wantErr: true},
+	}
+	for _, g := range golden {
+		got, err := srcSrv.Expand(g.srcFile, g.text)
+		if g.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", g.text, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unable to expand; %+v", g.text, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%q: expansion mismatch; expected %q, got %q", g.text, g.want, got)
+		}
+	}
+}
+
+func TestSrcSrvExpandExponential(t *testing.T) {
+	// V0=%V1%%V1%, V1=%V2%%V2%, ..., V31=x expands to 2^31 bytes.
+	for _, leaf := range []string{"x", ""} {
+		srcSrv := &SrcSrv{Vars: make(map[string]string)}
+		for i := 0; i < 31; i++ {
+			name := "V" + strings.Repeat("I", i)
+			next := "%V" + strings.Repeat("I", i+1) + "%"
+			srcSrv.Vars[name] = next + next
+		}
+		srcSrv.Vars["V"+strings.Repeat("I", 31)] = leaf
+		start := time.Now()
+		got, err := srcSrv.Expand(&SrcSrvFile{}, "%v%")
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%q: expansion took %v", leaf, elapsed)
+		}
+		if leaf == "" {
+			// Expands to an empty string; bounded in time by memoization.
+			if err != nil || got != "" {
+				t.Errorf("%q: expansion mismatch; expected (\"\", nil), got (%q, %v)", leaf, got, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%q: expected error, got %d bytes", leaf, len(got))
+		}
+	}
+}
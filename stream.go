@@ -0,0 +1,304 @@
+package pdb
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Stream is a stream of a PDB file.
+//
+// Stream is one of the following types.
+//
+//    *PrevStreamTableStream
+//    *PDBStream
+//    *TPIStream (TPI and IPI streams)
+//    *DBIStream
+//    *StringTable
+//    *LinkInfo
+//    *SourceHeaderBlock
+//    *SourceLink
+//    *SrcSrv
+//    *RawStream
+type Stream interface {
+	// StreamNum returns the stream number of the stream.
+	StreamNum() StreamNumber
+	// Name returns the name of the stream; or the empty string if unnamed.
+	Name() string
+	// Size returns the size in bytes of the stream.
+	Size() int64
+	// Kind returns the kind of the stream.
+	Kind() StreamKind
+}
+
+// streamMeta records the metadata common to all streams.
+type streamMeta struct {
+	// Stream number.
+	num StreamNumber
+	// Stream name; or empty if unnamed.
+	name string
+	// Size in bytes of stream.
+	size int64
+	// Stream kind.
+	kind StreamKind
+}
+
+// StreamNum returns the stream number of the stream.
+func (meta streamMeta) StreamNum() StreamNumber {
+	return meta.num
+}
+
+// Name returns the name of the stream; or the empty string if unnamed.
+func (meta streamMeta) Name() string {
+	return meta.name
+}
+
+// Size returns the size in bytes of the stream.
+func (meta streamMeta) Size() int64 {
+	return meta.size
+}
+
+// Kind returns the kind of the stream.
+func (meta streamMeta) Kind() StreamKind {
+	return meta.kind
+}
+
+// meta returns the metadata of the stream of the given stream reader, as a
+// stream of the given kind.
+func (sr *StreamReader) meta(kind StreamKind) streamMeta {
+	return streamMeta{
+		num:  StreamNumber(sr.streamNum),
+		name: sr.file.streamMetaName(StreamNumber(sr.streamNum)),
+		size: sr.size,
+		kind: kind,
+	}
+}
+
+//go:generate stringer -linecomment -type StreamKind
+
+// StreamKind specifies the kind of a stream.
+type StreamKind uint8
+
+// Stream kinds.
+const (
+	// Previous stream table (stream 0).
+	StreamKindPrevStreamTable StreamKind = iota + 1 // previous stream table
+	// PDB stream (stream 1).
+	StreamKindPDB // PDB stream
+	// TPI stream (stream 2).
+	StreamKindTPI // TPI stream
+	// DBI stream (stream 3).
+	StreamKindDBI // DBI stream
+	// IPI stream (stream 4).
+	StreamKindIPI // IPI stream
+	// Stream with undecoded contents.
+	StreamKindRaw // raw stream
+	// String table ("/names" stream).
+	StreamKindStringTable // string table
+	// Link info ("/LinkInfo" stream).
+	StreamKindLinkInfo // link info
+	// Source header block ("/src/headerblock" stream).
+	StreamKindSourceHeaderBlock // source header block
+	// Source Link ("sourcelink" stream).
+	StreamKindSourceLink // Source Link
+	// Source server ("srcsrv" stream).
+	StreamKindSrcSrv // source server
+)
+
+// RawStream is a stream whose contents have not been decoded, either as the
+// stream is not supported by the parser, was not selected for decoding (see
+// ParseOptions.Decode), or failed to decode in recovery mode. The contents of
+// the stream may be read using File.StreamReader or File.ReadStream.
+type RawStream struct {
+	streamMeta
+}
+
+// newRawStream returns a new raw stream of the given stream number.
+func (file *File) newRawStream(streamNum StreamNumber) *RawStream {
+	return &RawStream{
+		streamMeta: streamMeta{
+			num:  streamNum,
+			name: file.streamMetaName(streamNum),
+			size: int64(file.StreamTbl.StreamInfos[streamNum].Size),
+			kind: StreamKindRaw,
+		},
+	}
+}
+
+// PrevStreamTableStream is the stream containing the previous stream table
+// (stream 0).
+type PrevStreamTableStream struct {
+	streamMeta
+	// Previous stream table.
+	Tbl *StreamTable
+}
+
+// PDBInfo returns the PDB stream of the PDB file, decoding it if not already
+// present in file.Streams.
+//
+// The PDB stream is consulted while decoding other streams (e.g. to locate
+// named streams, or to determine the presence of the IPI stream).
+func (file *File) PDBInfo() (*PDBStream, error) {
+	stream, err := file.fixedStream(StreamIDPDBStream)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pdbStream, ok := stream.(*PDBStream)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %v", StreamIDPDBStream)
+	}
+	return pdbStream, nil
+}
+
+// TPI returns the TPI stream of the PDB file, decoding it if not already
+// present in file.Streams.
+func (file *File) TPI() (*TPIStream, error) {
+	stream, err := file.fixedStream(StreamIDTPIStream)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tpiStream, ok := stream.(*TPIStream)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %v", StreamIDTPIStream)
+	}
+	return tpiStream, nil
+}
+
+// IPI returns the IPI stream of the PDB file, decoding it if not already
+// present in file.Streams.
+func (file *File) IPI() (*TPIStream, error) {
+	stream, err := file.fixedStream(StreamIDIPIStream)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ipiStream, ok := stream.(*TPIStream)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %v; PDB file has no IPI stream", StreamIDIPIStream)
+	}
+	return ipiStream, nil
+}
+
+// DBI returns the DBI stream of the PDB file, decoding it if not already
+// present in file.Streams.
+func (file *File) DBI() (*DBIStream, error) {
+	stream, err := file.fixedStream(StreamIDDBIStream)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	dbiStream, ok := stream.(*DBIStream)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %v", StreamIDDBIStream)
+	}
+	return dbiStream, nil
+}
+
+// StreamByName returns the stream with the given name (e.g. "/names"), as
+// located by the named stream map of the PDB stream, decoding it if not
+// already present in file.Streams.
+func (file *File) StreamByName(name string) (Stream, error) {
+	streamNum, err := file.lookupStreamName(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stream, err := file.stream(streamNum)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return stream, nil
+}
+
+// lookupStreamName returns the stream number of the stream with the given name,
+// as located by the named stream map of the PDB stream.
+func (file *File) lookupStreamName(name string) (StreamNumber, error) {
+	streamNameMap, err := file.streamNameMap()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	streamNum, ok := streamNameMap.Lookup(name)
+	if !ok {
+		return 0, errors.Errorf("unable to locate stream %q in named stream map", name)
+	}
+	return streamNum, nil
+}
+
+// streamNameMap returns the named stream map of the PDB stream.
+func (file *File) streamNameMap() (*StreamNameMap, error) {
+	pdbStream, err := file.PDBInfo()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if pdbStream.StreamNameMap == nil {
+		return nil, errors.New("PDB stream has no named stream map")
+	}
+	return pdbStream.StreamNameMap, nil
+}
+
+// streamMetaName returns the name of the stream with the given stream number;
+// either the name of a fixed stream or the name of a named stream; or the empty
+// string if unnamed.
+func (file *File) streamMetaName(streamNum StreamNumber) string {
+	if name := fixedStreamName(streamNum); len(name) > 0 {
+		return name
+	}
+	streamNameMap, err := file.streamNameMap()
+	if err != nil {
+		return ""
+	}
+	for _, entry := range streamNameMap.Entries {
+		if entry.StreamNum == streamNum {
+			return entry.Name
+		}
+	}
+	return ""
+}
+
+// fixedStream returns the stream with the given fixed stream index.
+func (file *File) fixedStream(id StreamID) (Stream, error) {
+	if int(id) >= len(file.StreamTbl.StreamInfos) {
+		return nil, errors.Errorf("unable to locate %v; PDB file has %d streams", id, len(file.StreamTbl.StreamInfos))
+	}
+	stream, err := file.stream(StreamNumber(id))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if stream == nil {
+		return nil, errors.Errorf("unable to locate %v; nil stream", id)
+	}
+	return stream, nil
+}
+
+// stream returns the stream with the given stream number, as present in
+// file.Streams if decoded, and otherwise decoding it on demand. Streams decoded
+// on demand are decoded once and cached, but not stored in file.Streams, so
+// that the File remains safe for concurrent read-only use.
+func (file *File) stream(streamNum StreamNumber) (Stream, error) {
+	if int(streamNum) < len(file.Streams) {
+		switch stream := file.Streams[streamNum].(type) {
+		case nil, *RawStream:
+			// decode on demand.
+		default:
+			return stream, nil
+		}
+	}
+	if int(streamNum) >= len(file.streamCache) {
+		return nil, errors.Errorf("invalid stream number %d; expected < %d", streamNum, len(file.streamCache))
+	}
+	entry := &file.streamCache[streamNum]
+	entry.once.Do(func() {
+		entry.stream, entry.err = file.DecodeStream(streamNum)
+	})
+	if entry.err != nil {
+		return nil, errors.WithStack(entry.err)
+	}
+	return entry.stream, nil
+}
+
+// cachedStream is a stream decoded on demand, as cached by File.stream.
+type cachedStream struct {
+	// once guards the decoding of stream.
+	once sync.Once
+	// Decoded stream; or nil if decoding failed (see err).
+	stream Stream
+	// Error encountered while decoding the stream.
+	err error
+}
@@ -0,0 +1,213 @@
+package pdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// StreamNameMap maps from stream name to stream number. The named stream map
+// is stored in the PDB stream as a serialized hash table, mapping from offsets
+// into a string buffer of stream names to stream numbers.
+//
+// ref: https://llvm.org/docs/PDB/PdbStream.html#named-stream-map
+// ref: https://llvm.org/docs/PDB/HashTable.html
+type StreamNameMap struct {
+	// Number of entries in the hash table.
+	Size uint32
+	// Number of buckets in the hash table.
+	Capacity uint32
+	// Bit vector of present buckets; bucket i is present if bit i%32 of word
+	// i/32 is set.
+	Present []uint32
+	// Bit vector of deleted buckets.
+	Deleted []uint32
+	// Named streams, in stream number order.
+	Entries []NamedStream
+
+	// Map from stream name to stream number.
+	names map[string]StreamNumber
+}
+
+// NamedStream is an entry of the named stream map.
+type NamedStream struct {
+	// Stream name.
+	Name string
+	// Stream number.
+	StreamNum StreamNumber
+	// Offset of the stream name within the string buffer of the named stream
+	// map.
+	Offset uint32
+}
+
+// Lookup returns the stream number of the stream with the given name (e.g.
+// "/names"), and a boolean indicating whether the stream was found.
+func (m *StreamNameMap) Lookup(name string) (StreamNumber, bool) {
+	if m == nil {
+		return 0, false
+	}
+	streamNum, ok := m.names[name]
+	return streamNum, ok
+}
+
+// parseStreamNameMap parses the given named stream map, reading from r.
+func (file *File) parseStreamNameMap(r *StreamReader) (*StreamNameMap, error) {
+	// StringBufSize.
+	var stringBufSize uint32
+	if err := binary.Read(r, binary.LittleEndian, &stringBufSize); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if rem := r.Size() - r.off; int64(stringBufSize) > rem {
+		return nil, errors.Errorf("invalid size of named stream map string buffer; expected <= %d, got %d", rem, stringBufSize)
+	}
+	// StringBuf.
+	stringBuf := make([]byte, stringBufSize)
+	if _, err := io.ReadFull(r, stringBuf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Hash table.
+	m := &StreamNameMap{}
+	hashTbl, err := parseHashTable(r, 4)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	m.Size = hashTbl.size
+	m.Capacity = hashTbl.capacity
+	m.Present = hashTbl.present
+	m.Deleted = hashTbl.deleted
+	npresent := int(hashTbl.size)
+	m.names = make(map[string]StreamNumber, npresent)
+	for i := 0; i < npresent; i++ {
+		// Key; offset of stream name in string buffer.
+		var offset uint32
+		if err := binary.Read(r, binary.LittleEndian, &offset); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Value; stream number.
+		var streamNum uint32
+		if err := binary.Read(r, binary.LittleEndian, &streamNum); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		name, err := cString(stringBuf, offset)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if streamNum >= uint32(len(file.StreamTbl.StreamInfos)) {
+			return nil, errors.Errorf("invalid stream number %d of named stream %q; expected < %d", streamNum, name, len(file.StreamTbl.StreamInfos))
+		}
+		entry := NamedStream{
+			Name:      name,
+			StreamNum: StreamNumber(streamNum),
+			Offset:    offset,
+		}
+		m.Entries = append(m.Entries, entry)
+		m.names[name] = entry.StreamNum
+	}
+	sort.SliceStable(m.Entries, func(i, j int) bool {
+		return m.Entries[i].StreamNum < m.Entries[j].StreamNum
+	})
+	return m, nil
+}
+
+// hashTable is the header of a serialized hash table, as used by the named
+// stream map and the source header block. The key-value pairs of present
+// buckets follow the header.
+//
+// ref: https://llvm.org/docs/PDB/HashTable.html
+type hashTable struct {
+	// Number of entries in the hash table.
+	size uint32
+	// Number of buckets in the hash table.
+	capacity uint32
+	// Bit vector of present buckets.
+	present []uint32
+	// Bit vector of deleted buckets.
+	deleted []uint32
+}
+
+// parseHashTable parses the header of the given serialized hash table with
+// 4-byte keys and values of the given size in bytes, reading from r. The
+// remaining size of the stream is validated to hold the key-value pairs of
+// present buckets.
+func parseHashTable(r *StreamReader, valueSize int64) (*hashTable, error) {
+	// Size.
+	hashTbl := &hashTable{}
+	if err := binary.Read(r, binary.LittleEndian, &hashTbl.size); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// Capacity.
+	if err := binary.Read(r, binary.LittleEndian, &hashTbl.capacity); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if hashTbl.size > hashTbl.capacity {
+		return nil, errors.Errorf("invalid size of hash table; expected <= capacity %d, got %d", hashTbl.capacity, hashTbl.size)
+	}
+	// Present.
+	present, err := parseBitVector(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	hashTbl.present = present
+	// Deleted.
+	deleted, err := parseBitVector(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	hashTbl.deleted = deleted
+	npresent := 0
+	for i, word := range hashTbl.present {
+		npresent += bits.OnesCount32(word)
+		if word == 0 {
+			continue
+		}
+		// Highest present bucket of the word.
+		last := uint64(i)*32 + uint64(31-bits.LeadingZeros32(word))
+		if last >= uint64(hashTbl.capacity) {
+			return nil, errors.Errorf("invalid present bucket %d in hash table; expected < capacity %d", last, hashTbl.capacity)
+		}
+	}
+	if uint32(npresent) != hashTbl.size {
+		return nil, errors.Errorf("mismatch between size of hash table (%d) and number of present buckets (%d)", hashTbl.size, npresent)
+	}
+	// Key-value pairs of present buckets.
+	pairSize := 4 + valueSize
+	if rem := r.Size() - r.off; int64(npresent)*pairSize > rem {
+		return nil, errors.Errorf("hash table too short; expected %d bytes of key-value pairs, got %d bytes", int64(npresent)*pairSize, rem)
+	}
+	return hashTbl, nil
+}
+
+// parseBitVector parses the given serialized bit vector of a hash table,
+// reading from r.
+func parseBitVector(r *StreamReader) ([]uint32, error) {
+	// NWords.
+	var nwords uint32
+	if err := binary.Read(r, binary.LittleEndian, &nwords); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if rem := r.Size() - r.off; int64(nwords)*4 > rem {
+		return nil, errors.Errorf("invalid number of bit vector words; expected <= %d, got %d", rem/4, nwords)
+	}
+	// Words.
+	words := make([]uint32, nwords)
+	if err := binary.Read(r, binary.LittleEndian, &words); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return words, nil
+}
+
+// cString returns the NULL-terminated string at the given offset of buf.
+func cString(buf []byte, offset uint32) (string, error) {
+	if uint64(offset) >= uint64(len(buf)) {
+		return "", errors.Errorf("invalid string offset 0x%X; expected < 0x%X", offset, len(buf))
+	}
+	end := bytes.IndexByte(buf[offset:], 0)
+	if end == -1 {
+		return "", errors.Errorf("unterminated string at offset 0x%X", offset)
+	}
+	return string(buf[offset : int(offset)+end]), nil
+}
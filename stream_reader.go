@@ -0,0 +1,250 @@
+package pdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// StreamReader provides read access to the contents of a stream. Pages are read
+// on demand from the underlying reader of the PDB file, walking the page
+// number map of the stream without concatenating its pages up-front.
+//
+// StreamReader implements io.Reader, io.ReaderAt and io.Seeker.
+type StreamReader struct {
+	// PDB file containing the stream.
+	file *File
+	// Stream number; or -1 if not a stream (e.g. the stream table).
+	streamNum int
+	// Stream name, as used in error messages.
+	name string
+	// Maps from stream page number to page number.
+	pageNumMap []uint32
+	// Size in bytes of stream.
+	size int64
+	// Current read offset within the stream.
+	off int64
+	// Context of the decoding of the stream, checked for cancellation between
+	// pages and records; or nil if not cancellable.
+	ctx context.Context
+}
+
+// StreamReader returns a reader of the contents of the stream with the given
+// stream number. The contents of nil streams are read as empty; use
+// StreamInfo.IsNil to distinguish nil streams from zero-length streams.
+func (file *File) StreamReader(streamNum StreamNumber) (*StreamReader, error) {
+	sr, err := file.streamReader(file.StreamTbl, streamNum)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return sr, nil
+}
+
+// streamReader returns a reader of the contents of the stream with the given
+// stream number, as located by the given stream table.
+func (file *File) streamReader(streamTbl *StreamTable, streamNum StreamNumber) (*StreamReader, error) {
+	if int(streamNum) >= len(streamTbl.StreamInfos) {
+		return nil, errors.Errorf("invalid stream number %d; expected < %d", streamNum, len(streamTbl.StreamInfos))
+	}
+	streamInfo := streamTbl.StreamInfos[streamNum]
+	pageNumMap := streamTbl.PageNumMaps[streamNum]
+	sr := file.newStreamReader(streamName(streamNum), pageNumMap, streamInfo.Size)
+	sr.streamNum = int(streamNum)
+	return sr, nil
+}
+
+// newStreamReader returns a reader of the contents of the named stream of the
+// given size in bytes, located in the pages of the given page number map.
+func (file *File) newStreamReader(name string, pageNumMap []uint32, size int32) *StreamReader {
+	if size == nilStreamSize {
+		size = 0
+	}
+	return &StreamReader{
+		file:       file,
+		streamNum:  -1,
+		name:       name,
+		pageNumMap: pageNumMap,
+		size:       int64(size),
+	}
+}
+
+// streamName returns the name of the stream with the given stream number, as
+// used in error messages.
+func streamName(streamNum StreamNumber) string {
+	if name := fixedStreamName(streamNum); len(name) > 0 {
+		return name
+	}
+	return fmt.Sprintf("stream %d", streamNum)
+}
+
+// fixedStreamName returns the name of the stream with the given fixed stream
+// index; or the empty string if not a fixed stream.
+func fixedStreamName(streamNum StreamNumber) string {
+	switch id := StreamID(streamNum); id {
+	case StreamIDPrevStreamTable, StreamIDPDBStream, StreamIDTPIStream, StreamIDDBIStream, StreamIDIPIStream:
+		return id.String()
+	}
+	return ""
+}
+
+// Size returns the size in bytes of the stream.
+func (sr *StreamReader) Size() int64 {
+	return sr.size
+}
+
+// FileOffset returns the offset in bytes within the PDB file of the given
+// offset within the stream; or -1 if the offset is outside of the stream, or
+// located in an invalid page.
+func (sr *StreamReader) FileOffset(off int64) int64 {
+	if off < 0 || off >= sr.size {
+		return -1
+	}
+	pageSize := int64(sr.file.FileHdr.PageSize)
+	streamPageNum := off / pageSize
+	if streamPageNum >= int64(len(sr.pageNumMap)) {
+		return -1
+	}
+	pageOff, err := sr.file.pageOffset(sr.pageNumMap[streamPageNum])
+	if err != nil {
+		return -1
+	}
+	return pageOff + off%pageSize
+}
+
+// Read reads up to len(p) bytes from the current offset of the stream into p.
+func (sr *StreamReader) Read(p []byte) (int, error) {
+	if sr.off >= sr.size {
+		return 0, io.EOF
+	}
+	if rem := sr.size - sr.off; int64(len(p)) > rem {
+		p = p[:rem]
+	}
+	n, err := sr.ReadAt(p, sr.off)
+	sr.off += int64(n)
+	return n, err
+}
+
+// ReadAt reads len(p) bytes from the given offset of the stream into p.
+func (sr *StreamReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.Errorf("invalid negative stream offset %d", off)
+	}
+	pageSize := int64(sr.file.FileHdr.PageSize)
+	n := 0
+	for n < len(p) {
+		if off >= sr.size {
+			return n, io.EOF
+		}
+		if n > 0 {
+			// Check for cancellation between pages.
+			if err := sr.ctxErr(); err != nil {
+				return n, errors.WithStack(err)
+			}
+		}
+		// Read the remainder of the current page, or less if the end of the
+		// stream or the end of p is reached first.
+		streamPageNum := off / pageSize
+		pageOff := off % pageSize
+		chunk := pageSize - pageOff
+		if rem := sr.size - off; chunk > rem {
+			chunk = rem
+		}
+		if rem := int64(len(p) - n); chunk > rem {
+			chunk = rem
+		}
+		pageStart, err := sr.file.pageOffset(sr.pageNumMap[streamPageNum])
+		if err != nil {
+			return n, errors.WithStack(err)
+		}
+		fileOff := pageStart + pageOff
+		m, err := sr.file.r.ReadAt(p[n:n+int(chunk)], fileOff)
+		n += m
+		off += int64(m)
+		if err != nil && m < int(chunk) {
+			return n, errors.WithStack(err)
+		}
+	}
+	return n, nil
+}
+
+// ctxErr returns the error of the context of the stream reader if cancelled;
+// or nil otherwise.
+func (sr *StreamReader) ctxErr() error {
+	if sr.ctx == nil {
+		return nil
+	}
+	return sr.ctx.Err()
+}
+
+// Seek sets the offset for the next Read, interpreted according to whence (see
+// io.Seeker).
+func (sr *StreamReader) Seek(offset int64, whence int) (int64, error) {
+	var off int64
+	switch whence {
+	case io.SeekStart:
+		off = offset
+	case io.SeekCurrent:
+		off = sr.off + offset
+	case io.SeekEnd:
+		off = sr.size + offset
+	default:
+		return 0, errors.Errorf("invalid whence %d", whence)
+	}
+	if off < 0 {
+		return 0, errors.Errorf("invalid negative stream offset %d", off)
+	}
+	sr.off = off
+	return off, nil
+}
+
+// Bytes returns n bytes of the stream starting at the given offset. If the
+// underlying reader holds the PDB file in memory (e.g. OpenMmap) and the range
+// is stored in consecutive pages, the returned slice aliases the file contents
+// without copying, and must not be modified; otherwise, the range is copied
+// into a new slice.
+func (sr *StreamReader) Bytes(off, n int64) ([]byte, error) {
+	if off < 0 || n < 0 || off > sr.size || n > sr.size-off {
+		return nil, errors.Errorf("invalid stream range [0x%X, 0x%X); expected within stream of size 0x%X", off, off+n, sr.size)
+	}
+	if s, ok := sr.file.r.(slicer); ok && n > 0 {
+		if start, ok := sr.contiguous(off, n); ok {
+			buf, err := s.slice(start, n)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return buf, nil
+		}
+	}
+	buf := make([]byte, n)
+	if _, err := sr.ReadAt(buf, off); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return buf, nil
+}
+
+// contiguous reports whether the given non-empty range of the stream is stored
+// in consecutive pages of the PDB file, and if so, returns the file offset of
+// the range.
+func (sr *StreamReader) contiguous(off, n int64) (int64, bool) {
+	pageSize := int64(sr.file.FileHdr.PageSize)
+	first := off / pageSize
+	last := (off + n - 1) / pageSize
+	for i := first; i < last; i++ {
+		if sr.pageNumMap[i+1] != sr.pageNumMap[i]+1 {
+			return 0, false
+		}
+	}
+	pageStart, err := sr.file.pageOffset(sr.pageNumMap[first])
+	if err != nil {
+		return 0, false
+	}
+	// Validate the page numbers of the remaining pages.
+	for i := first + 1; i <= last; i++ {
+		if _, err := sr.file.pageOffset(sr.pageNumMap[i]); err != nil {
+			return 0, false
+		}
+	}
+	return pageStart + off%pageSize, true
+}
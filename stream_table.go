@@ -3,7 +3,6 @@ package pdb
 import (
 	"encoding/binary"
 	"io"
-	"math"
 
 	"github.com/pkg/errors"
 )
@@ -21,7 +20,7 @@ import (
 //    type StreamTable struct {
 //       NStreams = uint32(4)
 //       StreamInfos = []StreamInfo{{Size: 1000}, {Size: 8000}, {Size: 16000}, {Size: 9000}}
-//       PageNumMaps = [][]uint16{
+//       PageNumMaps = [][]uint32{
 //          {4},
 //          {5, 6},
 //          {11, 9, 7, 8},
@@ -34,34 +33,126 @@ import (
 type StreamTable struct {
 	// Number of streams.
 	NStreams uint32
-	// Stream information about each stream of the MSF.
+	// Stream information about each stream of the MSF. Unused stream slots are
+	// marked as nil streams (see StreamInfo.IsNil).
 	StreamInfos []StreamInfo // length: NStreams
 	// Maps from stream number and stream page number to page number. Note that
 	// the array is jagged, and as such, the length of the page number slices may
-	// differ.
-	PageNumMaps [][]uint16 // length of PageNumMaps[i]: math.Ceil(streamTbl.StreamInfos[i].Size / msfHdr.PageSize)
+	// differ. Page numbers are stored as uint16 in MSF 2.00 and as uint32 in MSF
+	// 7.00.
+	PageNumMaps [][]uint32 // length of PageNumMaps[i]: math.Ceil(streamTbl.StreamInfos[i].Size / msfHdr.PageSize); 0 for nil streams
 }
 
-// parseStreamTable parses the given stream table, reading from r.
-func (file *File) parseStreamTable(r io.Reader) (*StreamTable, error) {
+// parseStreamTable parses the given stream table, reading from sr.
+func (file *File) parseStreamTable(sr *StreamReader) (*StreamTable, error) {
 	// NStreams.
 	streamTbl := &StreamTable{}
-	if err := binary.Read(r, binary.LittleEndian, &streamTbl.NStreams); err != nil {
+	if err := binary.Read(sr, binary.LittleEndian, &streamTbl.NStreams); err != nil {
 		return nil, errors.WithStack(err)
 	}
 	// StreamInfos.
+	entrySize := int64(8) // size in bytes of stream information entry.
+	if file.FileHdr.Version == MSFVersion700 {
+		entrySize = 4
+	}
+	if rem := sr.Size() - 4; int64(streamTbl.NStreams) > rem/entrySize {
+		return nil, errors.Errorf("invalid number of streams %d; stream table of %d bytes holds at most %d streams", streamTbl.NStreams, sr.Size(), rem/entrySize)
+	}
 	streamTbl.StreamInfos = make([]StreamInfo, streamTbl.NStreams)
-	if err := binary.Read(r, binary.LittleEndian, &streamTbl.StreamInfos); err != nil {
-		return nil, errors.WithStack(err)
+	switch file.FileHdr.Version {
+	case MSFVersion200:
+		if err := binary.Read(sr, binary.LittleEndian, &streamTbl.StreamInfos); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case MSFVersion700:
+		// Only stream sizes are stored in MSF 7.00.
+		sizes := make([]int32, streamTbl.NStreams)
+		if err := binary.Read(sr, binary.LittleEndian, &sizes); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for i, size := range sizes {
+			streamTbl.StreamInfos[i].Size = size
+		}
+	default:
+		return nil, errors.Errorf("support for MSF version %v not yet implemented", file.FileHdr.Version)
+	}
+	// Validate stream sizes.
+	maxSize := int64(file.FileHdr.NPages) * int64(file.FileHdr.PageSize)
+	for streamNum, streamInfo := range streamTbl.StreamInfos {
+		if streamInfo.IsNil() {
+			continue
+		}
+		if streamInfo.Size < 0 || int64(streamInfo.Size) > maxSize {
+			return nil, errors.Errorf("invalid size of stream %d; expected >= 0 and <= %d, got %d", streamNum, maxSize, streamInfo.Size)
+		}
 	}
 	// PageNumMaps.
-	streamTbl.PageNumMaps = make([][]uint16, streamTbl.NStreams)
+	pageNumSize := int64(2) // size in bytes of page number.
+	if file.FileHdr.Version == MSFVersion700 {
+		pageNumSize = 4
+	}
+	streamTbl.PageNumMaps = make([][]uint32, streamTbl.NStreams)
 	for i := range streamTbl.PageNumMaps {
-		streamNPages := int(math.Ceil(float64(streamTbl.StreamInfos[i].Size) / float64(file.FileHdr.PageSize)))
-		streamTbl.PageNumMaps[i] = make([]uint16, streamNPages)
-		if err := binary.Read(r, binary.LittleEndian, &streamTbl.PageNumMaps[i]); err != nil {
+		streamNPages := streamTbl.StreamInfos[i].NPages(file.FileHdr.PageSize)
+		if rem := sr.Size() - sr.off; int64(streamNPages) > rem/pageNumSize {
+			return nil, errors.Errorf("page number map of stream %d (%d pages) exceeds end of stream table", i, streamNPages)
+		}
+		pageNumMap, err := file.parsePageNumMap(sr, streamNPages)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		streamTbl.PageNumMaps[i] = pageNumMap
+	}
+	if err := file.validateStreamTable(streamTbl); err != nil {
+		// Invalid page numbers are reported when reading the contents of the
+		// affected streams.
+		if !file.opts.Recover {
 			return nil, errors.WithStack(err)
 		}
+		file.addDiagnostic(SeverityWarning, sr.formatError(err))
 	}
 	return streamTbl, nil
 }
+
+// validateStreamTable validates the page numbers of the given stream table,
+// reporting pages outside of the MSF, pages reserved for the MSF header and
+// free page maps, and pages claimed by more than one stream.
+func (file *File) validateStreamTable(streamTbl *StreamTable) error {
+	owners := make(map[uint32]int) // maps from page number to stream number.
+	for streamNum, pageNumMap := range streamTbl.PageNumMaps {
+		for _, pageNum := range pageNumMap {
+			if err := file.validatePageNum(pageNum); err != nil {
+				return errors.Wrapf(err, "invalid page of stream %d", streamNum)
+			}
+			if file.isReservedPage(pageNum) {
+				return errors.Errorf("invalid page of stream %d; page %d is reserved for MSF header or free page map", streamNum, pageNum)
+			}
+			if owner, ok := owners[pageNum]; ok {
+				return errors.Errorf("overlapping streams; page %d claimed by both stream %d and stream %d", pageNum, owner, streamNum)
+			}
+			owners[pageNum] = streamNum
+		}
+	}
+	return nil
+}
+
+// parsePageNumMap parses a page number map of the given number of pages,
+// reading from r. Page numbers are stored as uint16 in MSF 2.00 and as uint32
+// in MSF 7.00.
+func (file *File) parsePageNumMap(r io.Reader, npages int) ([]uint32, error) {
+	pageNumMap := make([]uint32, npages)
+	if file.FileHdr.Version == MSFVersion700 {
+		if err := binary.Read(r, binary.LittleEndian, &pageNumMap); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return pageNumMap, nil
+	}
+	pageNumMap16 := make([]uint16, npages)
+	if err := binary.Read(r, binary.LittleEndian, &pageNumMap16); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i, pageNum := range pageNumMap16 {
+		pageNumMap[i] = uint32(pageNum)
+	}
+	return pageNumMap, nil
+}
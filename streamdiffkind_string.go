@@ -0,0 +1,27 @@
+// Code generated by "stringer -linecomment -type StreamDiffKind"; DO NOT EDIT.
+
+package pdb
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[StreamDiffAdded-1]
+	_ = x[StreamDiffRemoved-2]
+	_ = x[StreamDiffResized-3]
+	_ = x[StreamDiffMoved-4]
+}
+
+const _StreamDiffKind_name = "addedremovedresizedmoved"
+
+var _StreamDiffKind_index = [...]uint8{0, 5, 12, 19, 24}
+
+func (i StreamDiffKind) String() string {
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_StreamDiffKind_index)-1 {
+		return "StreamDiffKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _StreamDiffKind_name[_StreamDiffKind_index[idx]:_StreamDiffKind_index[idx+1]]
+}
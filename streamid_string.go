@@ -11,15 +11,18 @@ func _() {
 	_ = x[StreamIDPrevStreamTable-0]
 	_ = x[StreamIDPDBStream-1]
 	_ = x[StreamIDTPIStream-2]
+	_ = x[StreamIDDBIStream-3]
+	_ = x[StreamIDIPIStream-4]
 }
 
-const _StreamID_name = "previous stream tablePDB streamTPI stream"
+const _StreamID_name = "previous stream tablePDB streamTPI streamDBI streamIPI stream"
 
-var _StreamID_index = [...]uint8{0, 21, 31, 41}
+var _StreamID_index = [...]uint8{0, 21, 31, 41, 51, 61}
 
 func (i StreamID) String() string {
-	if i >= StreamID(len(_StreamID_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_StreamID_index)-1 {
 		return "StreamID(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _StreamID_name[_StreamID_index[i]:_StreamID_index[i+1]]
+	return _StreamID_name[_StreamID_index[idx]:_StreamID_index[idx+1]]
 }
@@ -0,0 +1,34 @@
+// Code generated by "stringer -linecomment -type StreamKind"; DO NOT EDIT.
+
+package pdb
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[StreamKindPrevStreamTable-1]
+	_ = x[StreamKindPDB-2]
+	_ = x[StreamKindTPI-3]
+	_ = x[StreamKindDBI-4]
+	_ = x[StreamKindIPI-5]
+	_ = x[StreamKindRaw-6]
+	_ = x[StreamKindStringTable-7]
+	_ = x[StreamKindLinkInfo-8]
+	_ = x[StreamKindSourceHeaderBlock-9]
+	_ = x[StreamKindSourceLink-10]
+	_ = x[StreamKindSrcSrv-11]
+}
+
+const _StreamKind_name = "previous stream tablePDB streamTPI streamDBI streamIPI streamraw streamstring tablelink infosource header blockSource Linksource server"
+
+var _StreamKind_index = [...]uint8{0, 21, 31, 41, 51, 61, 71, 83, 92, 111, 122, 135}
+
+func (i StreamKind) String() string {
+	idx := int(i) - 1
+	if i < 1 || idx >= len(_StreamKind_index)-1 {
+		return "StreamKind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _StreamKind_name[_StreamKind_index[idx]:_StreamKind_index[idx+1]]
+}
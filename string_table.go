@@ -0,0 +1,222 @@
+package pdb
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// StringTable is the string table of the PDB file, stored in the "/names"
+// stream. Line tables, file checksums and symbol records refer to strings by
+// offset into the string table.
+//
+// ref: https://llvm.org/docs/PDB/StringTable.html
+type StringTable struct {
+	streamMeta
+	// String table header.
+	Hdr *StringTableHeader
+	// String buffer of NULL-terminated strings; starts with the empty string at
+	// offset 0.
+	Buf []byte
+	// Hash table of string offsets, indexed by hash of string; zero for empty
+	// buckets.
+	Buckets []uint32
+	// Number of strings in the string table.
+	NameCount uint32
+}
+
+// StringTableStreamName is the name of the string table stream, as located by
+// the named stream map.
+const StringTableStreamName = "/names"
+
+// Names returns the string table of the PDB file, as stored in the "/names"
+// stream, decoding it if not already present in file.Streams.
+func (file *File) Names() (*StringTable, error) {
+	stream, err := file.StreamByName(StringTableStreamName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	strTbl, ok := stream.(*StringTable)
+	if !ok {
+		return nil, errors.Errorf("unable to decode %q stream", StringTableStreamName)
+	}
+	return strTbl, nil
+}
+
+// String returns the string at the given offset of the string table.
+func (strTbl *StringTable) String(offset uint32) (string, error) {
+	s, err := cString(strTbl.Buf, offset)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return s, nil
+}
+
+// Lookup returns the offset of the given string in the string table, and a
+// boolean indicating whether the string was found. The string is located
+// through the hash table of the string table, using the hash function of its
+// hash version.
+func (strTbl *StringTable) Lookup(s string) (uint32, bool) {
+	nbuckets := uint32(len(strTbl.Buckets))
+	if nbuckets == 0 {
+		return 0, false
+	}
+	var hash uint32
+	switch strTbl.Hdr.HashVersion {
+	case StringTableHashV1:
+		hash = hashStringV1(s)
+	case StringTableHashV2:
+		hash = hashStringV2(s)
+	default:
+		return 0, false
+	}
+	// Linear probing, starting at the bucket of the hash.
+	start := hash % nbuckets
+	for i := uint32(0); i < nbuckets; i++ {
+		offset := strTbl.Buckets[(start+i)%nbuckets]
+		if offset == 0 {
+			// Empty bucket; string not present.
+			return 0, false
+		}
+		if t, err := strTbl.String(offset); err == nil && t == s {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+// StringTableHeader is a header of the string table.
+type StringTableHeader struct {
+	// Signature; always 0xEFFEEFFE.
+	Signature uint32
+	// Version of the hash function used by the hash table.
+	HashVersion StringTableHashVersion
+	// Size in bytes of the string buffer.
+	ByteSize uint32
+}
+
+// stringTableSignature is the signature of string table headers.
+const stringTableSignature = 0xEFFEEFFE
+
+//go:generate stringer -linecomment -type StringTableHashVersion
+
+// StringTableHashVersion specifies the version of the hash function used by
+// the hash table of a string table.
+type StringTableHashVersion uint32
+
+// String table hash versions.
+const (
+	StringTableHashV1 StringTableHashVersion = 1 // V1
+	StringTableHashV2 StringTableHashVersion = 2 // V2
+)
+
+// parseStringTable parses the given string table, reading from r.
+func (file *File) parseStringTable(r *StreamReader) (*StringTable, error) {
+	// Parse string table header.
+	strTbl := &StringTable{}
+	hdr, err := file.parseStringTableHeader(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	strTbl.Hdr = hdr
+	// Buf.
+	if rem := r.Size() - r.off; int64(hdr.ByteSize) > rem {
+		return nil, errors.Errorf("invalid size of string buffer; expected <= %d, got %d", rem, hdr.ByteSize)
+	}
+	strTbl.Buf = make([]byte, hdr.ByteSize)
+	if _, err := io.ReadFull(r, strTbl.Buf); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// NBuckets.
+	var nbuckets uint32
+	if err := binary.Read(r, binary.LittleEndian, &nbuckets); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if rem := r.Size() - r.off; int64(nbuckets)*4 > rem {
+		return nil, errors.Errorf("invalid number of string table buckets; expected <= %d, got %d", rem/4, nbuckets)
+	}
+	// Buckets.
+	strTbl.Buckets = make([]uint32, nbuckets)
+	if err := binary.Read(r, binary.LittleEndian, &strTbl.Buckets); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// NameCount.
+	if err := binary.Read(r, binary.LittleEndian, &strTbl.NameCount); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return strTbl, nil
+}
+
+// parseStringTableHeader parses the given string table header.
+func (file *File) parseStringTableHeader(r io.Reader) (*StringTableHeader, error) {
+	// Signature.
+	hdr := &StringTableHeader{}
+	if err := binary.Read(r, binary.LittleEndian, &hdr.Signature); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if hdr.Signature != stringTableSignature {
+		return nil, errors.Errorf("invalid string table signature 0x%08X; expected 0x%08X", hdr.Signature, uint32(stringTableSignature))
+	}
+	// HashVersion.
+	if err := binary.Read(r, binary.LittleEndian, &hdr.HashVersion); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch hdr.HashVersion {
+	case StringTableHashV1, StringTableHashV2:
+		// valid hash version.
+	default:
+		return nil, errors.Errorf("unsupported string table hash version %d", uint32(hdr.HashVersion))
+	}
+	// ByteSize.
+	if err := binary.Read(r, binary.LittleEndian, &hdr.ByteSize); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return hdr, nil
+}
+
+// hashStringV1 returns the V1 hash of the given string, as used by the hash
+// tables of string tables with hash version 1.
+//
+// ref: LHashPbCb in PDB/include/misc.h
+func hashStringV1(s string) uint32 {
+	buf := []byte(s)
+	var hash uint32
+	// Hash 4 bytes at the time.
+	for ; len(buf) >= 4; buf = buf[4:] {
+		hash ^= binary.LittleEndian.Uint32(buf)
+	}
+	// Hash remaining 2 bytes if present, then remaining byte if present.
+	if len(buf) >= 2 {
+		hash ^= uint32(binary.LittleEndian.Uint16(buf))
+		buf = buf[2:]
+	}
+	if len(buf) == 1 {
+		hash ^= uint32(buf[0])
+	}
+	const toLowerMask = 0x20202020
+	hash |= toLowerMask
+	hash ^= hash >> 11
+	return hash ^ (hash >> 16)
+}
+
+// hashStringV2 returns the V2 hash of the given string, as used by the hash
+// tables of string tables with hash version 2.
+//
+// ref: HashStringV2 in llvm/lib/DebugInfo/PDB/Native/Hash.cpp
+func hashStringV2(s string) uint32 {
+	buf := []byte(s)
+	hash := uint32(0xB170A1BF)
+	// Hash 4 bytes at the time, then the remaining bytes one at the time.
+	for ; len(buf) >= 4; buf = buf[4:] {
+		hash += binary.LittleEndian.Uint32(buf)
+		hash += hash << 10
+		hash ^= hash >> 6
+	}
+	for _, b := range buf {
+		hash += uint32(b)
+		hash += hash << 10
+		hash ^= hash >> 6
+	}
+	return hash*1664525 + 1013904223
+}
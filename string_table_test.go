@@ -0,0 +1,104 @@
+package pdb
+
+import "testing"
+
+// Expected hashes were computed independently of this package, by a direct
+// transliteration of the reference hash functions (LHashPbCb in
+// PDB/include/misc.h, and hashStringV2 in
+// llvm/lib/DebugInfo/PDB/Native/Hash.cpp), without reducing modulo the number
+// of buckets.
+
+func TestHashStringV1(t *testing.T) {
+	golden := []struct {
+		s    string
+		want uint32
+	}{
+		{s: "", want: 0x20240400},
+		{s: "a", want: 0x20240441},
+		{s: "ab", want: 0x20244649},
+		{s: "abc", want: 0x2024460A},
+		{s: "abcd", want: 0x646F8A62},
+		{s: "abcde", want: 0x646F8A27},
+		{s: "/names", want: 0x6D6CFC21},
+		{s: `c:\src\main.c`, want: 0x30680D9D},
+		{s: "\xFF\xFE\xFD", want: 0x2024DA19},
+		{s: "hello world", want: 0x233F6D6B},
+	}
+	for _, g := range golden {
+		if got := hashStringV1(g.s); got != g.want {
+			t.Errorf("%q: V1 hash mismatch; expected 0x%08X, got 0x%08X", g.s, g.want, got)
+		}
+	}
+}
+
+func TestHashStringV2(t *testing.T) {
+	golden := []struct {
+		s    string
+		want uint32
+	}{
+		{s: "", want: 0xEB404412},
+		{s: "a", want: 0x42C5F9E7},
+		{s: "ab", want: 0x7F18D49D},
+		{s: "abc", want: 0x7A29E978},
+		{s: "abcd", want: 0x5BCE33CF},
+		{s: "abcde", want: 0xB443E392},
+		{s: "/names", want: 0xF8ED89FB},
+		{s: `c:\src\main.c`, want: 0x6C0EEC1F},
+		{s: "\xFF\xFE\xFD", want: 0x804F5F89},
+		{s: "hello world", want: 0x4F2033FF},
+	}
+	for _, g := range golden {
+		if got := hashStringV2(g.s); got != g.want {
+			t.Errorf("%q: V2 hash mismatch; expected 0x%08X, got 0x%08X", g.s, g.want, got)
+		}
+	}
+}
+
+func TestStringTableLookup(t *testing.T) {
+	// V1 hashes modulo 6 buckets: "a" -> 5, "y" -> 5, "abcd" -> 0, "ab" -> 1,
+	// "abc" -> 4, "foo" -> 2, `c:\src\main.c` -> 1.
+	//
+	// Inserted in order "a", "y", "abcd", "ab"; "y" wraps around to bucket 0,
+	// which pushes "abcd" to bucket 1 and "ab" to bucket 2.
+	strTbl := &StringTable{
+		Hdr:     &StringTableHeader{Signature: stringTableSignature, HashVersion: StringTableHashV1},
+		Buf:     []byte("\x00a\x00y\x00abcd\x00ab\x00"),
+		Buckets: []uint32{3, 5, 10, 0, 0, 1},
+	}
+	golden := []struct {
+		s      string
+		want   uint32
+		wantOK bool
+	}{
+		// Found in bucket of hash.
+		{s: "a", want: 1, wantOK: true},
+		// Found after wraparound from last to first bucket.
+		{s: "y", want: 3, wantOK: true},
+		// Found after probing one and two buckets.
+		{s: "abcd", want: 5, wantOK: true},
+		{s: "ab", want: 10, wantOK: true},
+		// Empty bucket of hash.
+		{s: "abc", wantOK: false},
+		// Empty bucket reached after probing.
+		{s: "foo", wantOK: false},
+		{s: `c:\src\main.c`, wantOK: false},
+	}
+	for _, g := range golden {
+		got, ok := strTbl.Lookup(g.s)
+		if ok != g.wantOK || got != g.want {
+			t.Errorf("%q: lookup mismatch; expected (%d, %v), got (%d, %v)", g.s, g.want, g.wantOK, got, ok)
+		}
+	}
+	// Lookup terminates in a full hash table without empty buckets.
+	full := &StringTable{
+		Hdr:     &StringTableHeader{Signature: stringTableSignature, HashVersion: StringTableHashV2},
+		Buf:     []byte("\x00a\x00"),
+		Buckets: []uint32{1},
+	}
+	if got, ok := full.Lookup("a"); !ok || got != 1 {
+		t.Errorf("%q: lookup mismatch in full hash table; expected (1, true), got (%d, %v)", "a", got, ok)
+	}
+	if got, ok := full.Lookup("b"); ok {
+		t.Errorf("%q: expected not found in full hash table, got offset %d", "b", got)
+	}
+}
@@ -0,0 +1,315 @@
+package pdb
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPDBNotFound is returned by SymbolLocator.Locate if no matching PDB file
+// was found along the symbol search path.
+var ErrPDBNotFound = errors.New("matching PDB file not found")
+
+// SymbolLocator locates the PDB files of PE/COFF images along a symbol search
+// path, the way Windows debuggers do.
+type SymbolLocator struct {
+	// Symbol search path.
+	Path SymbolPath
+	// HTTP client used to fetch PDB files from symbol servers;
+	// http.DefaultClient if nil.
+	Client *http.Client
+	// Default local cache directory, used by symbol server elements without
+	// an explicit cache directory (e.g. "srv*https://example.com/symbols"); if
+	// empty, such PDB files are not cached and can not be fetched.
+	DefaultCache string
+}
+
+// Locate returns the local path of the PDB file matching the given CodeView
+// debug information. Elements of the symbol search path are searched in order;
+// plain directories are searched both as flat directories and as symbol
+// stores, and symbol servers are searched through their local caches before
+// fetching from the symbol store into the first cache.
+//
+// Candidate PDB files are verified against the CodeView debug information (see
+// File.MatchCodeView) before being accepted; a downloaded PDB file is only
+// stored in the cache if it matches. ErrPDBNotFound is returned if no matching
+// PDB file was found.
+func (l *SymbolLocator) Locate(ctx context.Context, cv *CodeViewInfo) (string, error) {
+	key, err := SymstoreKey(cv)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	// Cache directory of symbol files located by subsequent elements, as
+	// specified by cache* elements.
+	var cacheDir string
+	for _, elem := range l.Path {
+		if err := ctx.Err(); err != nil {
+			return "", errors.WithStack(err)
+		}
+		switch elem.Kind {
+		case SymbolPathCache:
+			cacheDir = elem.Dir
+			if len(cacheDir) == 0 {
+				cacheDir = l.DefaultCache
+			}
+			if len(cacheDir) > 0 {
+				if pdbPath, ok := findPDB(cv, storePath(cacheDir, key)); ok {
+					return pdbPath, nil
+				}
+			}
+		case SymbolPathDir:
+			pdbPath, ok := findPDB(cv, filepath.Join(elem.Dir, path.Base(key)), storePath(elem.Dir, key))
+			if !ok {
+				continue
+			}
+			if len(cacheDir) > 0 {
+				return copyToCache(cv, pdbPath, storePath(cacheDir, key))
+			}
+			return pdbPath, nil
+		case SymbolPathServer:
+			pdbPath, err := l.locateServer(ctx, cv, key, elem, cacheDir)
+			if err != nil {
+				if errors.Cause(err) == ErrPDBNotFound {
+					continue
+				}
+				return "", errors.WithStack(err)
+			}
+			return pdbPath, nil
+		}
+	}
+	return "", errors.Wrapf(ErrPDBNotFound, "unable to locate %q", key)
+}
+
+// locateServer locates the PDB file matching the given CodeView debug
+// information and symbol store key using the given symbol server element,
+// searching its local caches before fetching from its symbol store into the
+// first cache (or the given cache directory of a preceding cache* element).
+func (l *SymbolLocator) locateServer(ctx context.Context, cv *CodeViewInfo, key string, elem SymbolPathElem, cacheDir string) (string, error) {
+	var caches []string
+	for _, cache := range elem.Caches {
+		if len(cache) == 0 {
+			cache = l.DefaultCache
+		}
+		if len(cache) > 0 {
+			caches = append(caches, cache)
+		}
+	}
+	if len(caches) == 0 {
+		switch {
+		case len(cacheDir) > 0:
+			caches = append(caches, cacheDir)
+		case len(l.DefaultCache) > 0:
+			caches = append(caches, l.DefaultCache)
+		}
+	}
+	for _, cache := range caches {
+		if pdbPath, ok := findPDB(cv, storePath(cache, key)); ok {
+			return pdbPath, nil
+		}
+	}
+	// Symbol store located in a local directory (e.g. UNC path).
+	if !isURL(elem.Store) {
+		pdbPath, ok := findPDB(cv, storePath(elem.Store, key))
+		if !ok {
+			return "", errors.WithStack(ErrPDBNotFound)
+		}
+		if len(caches) > 0 {
+			return copyToCache(cv, pdbPath, storePath(caches[0], key))
+		}
+		return pdbPath, nil
+	}
+	if len(caches) == 0 {
+		return "", errors.Errorf("unable to fetch %q from symbol server %q; no cache directory", key, elem.Store)
+	}
+	pdbPath, err := l.fetch(ctx, cv, key, elem.Store, storePath(caches[0], key))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return pdbPath, nil
+}
+
+// fetch fetches the PDB file matching the given CodeView debug information and
+// symbol store key from the symbol store at the given URL, storing it at
+// dstPath if it matches.
+//
+// If the symbol store has no PDB file but a file pointer (file.ptr) to a
+// local PDB file, the PDB file is copied from its local path instead.
+func (l *SymbolLocator) fetch(ctx context.Context, cv *CodeViewInfo, key, storeURL, dstPath string) (string, error) {
+	resp, err := l.get(ctx, storeURL, key)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if resp == nil {
+		// Try file pointer.
+		ptrKey := path.Join(path.Dir(key), "file.ptr")
+		ptrResp, err := l.get(ctx, storeURL, ptrKey)
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		if ptrResp == nil {
+			return "", errors.WithStack(ErrPDBNotFound)
+		}
+		defer ptrResp.Body.Close()
+		ptr, err := ioutil.ReadAll(io.LimitReader(ptrResp.Body, 4096))
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		srcPath, err := parseFilePtr(string(ptr))
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid file pointer %q of symbol store %q", ptrKey, storeURL)
+		}
+		if _, ok := findPDB(cv, srcPath); !ok {
+			return "", errors.WithStack(ErrPDBNotFound)
+		}
+		return copyToCache(cv, srcPath, dstPath)
+	}
+	defer resp.Body.Close()
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	// Download to temporary file, and only move into place once verified.
+	tmp, err := ioutil.TempFile(filepath.Dir(dstPath), ".download-*.pdb")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", errors.Wrapf(err, "unable to download %q from symbol server %q", key, storeURL)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err := matchPDB(cv, tmp.Name()); err != nil {
+		return "", errors.Wrapf(err, "invalid PDB file %q downloaded from symbol server %q", key, storeURL)
+	}
+	if err := os.Rename(tmp.Name(), dstPath); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return dstPath, nil
+}
+
+// get requests the file of the given key from the symbol store at the given
+// URL. Each path segment of the key is escaped. A nil response is returned if
+// the file was not found.
+func (l *SymbolLocator) get(ctx context.Context, storeURL, key string) (*http.Response, error) {
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	segs := strings.Split(key, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(storeURL, "/")+"/"+strings.Join(segs, "/"), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, nil
+	}
+	resp.Body.Close()
+	return nil, errors.Errorf("unable to fetch %q from symbol server %q; %s", key, storeURL, resp.Status)
+}
+
+// parseFilePtr parses the given symbol store file pointer (file.ptr), returning
+// the path of the file pointed to. File pointers have the form "PATH:<path>",
+// or "MSG:<message>" if the file is not available.
+func parseFilePtr(ptr string) (string, error) {
+	ptr = strings.TrimSpace(ptr)
+	switch {
+	case strings.HasPrefix(ptr, "PATH:"):
+		return strings.TrimPrefix(ptr, "PATH:"), nil
+	case strings.HasPrefix(ptr, "MSG:"):
+		return "", errors.Errorf("file not available: %s", strings.TrimPrefix(ptr, "MSG:"))
+	}
+	return "", errors.New("expected PATH: or MSG: prefix")
+}
+
+// findPDB returns the first of the given candidate paths of a PDB file which
+// matches the given CodeView debug information.
+func findPDB(cv *CodeViewInfo, candidates ...string) (string, bool) {
+	for _, pdbPath := range candidates {
+		if err := matchPDB(cv, pdbPath); err == nil {
+			return pdbPath, true
+		}
+	}
+	return "", false
+}
+
+// matchPDB reports whether the PDB file at pdbPath matches the given CodeView
+// debug information; a nil error is returned if it matches.
+func matchPDB(cv *CodeViewInfo, pdbPath string) error {
+	file, err := OpenMmap(pdbPath, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+	if err := file.MatchCodeView(cv); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// copyToCache copies the PDB file at srcPath to dstPath within a local cache
+// directory, returning dstPath.
+func copyToCache(cv *CodeViewInfo, srcPath, dstPath string) (string, error) {
+	if _, ok := findPDB(cv, dstPath); ok {
+		return dstPath, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return "", errors.WithStack(err)
+	}
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer src.Close()
+	tmp, err := ioutil.TempFile(filepath.Dir(dstPath), ".copy-*.pdb")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		return "", errors.WithStack(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	if err := os.Rename(tmp.Name(), dstPath); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return dstPath, nil
+}
+
+// storePath returns the local path of the file with the given key within the
+// symbol store rooted at dir.
+func storePath(dir, key string) string {
+	return filepath.Join(dir, filepath.FromSlash(key))
+}
+
+// isURL reports whether the given symbol store is located by an HTTP(S) URL.
+func isURL(store string) bool {
+	u, err := url.Parse(store)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
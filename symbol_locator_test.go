@@ -0,0 +1,254 @@
+package pdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testCodeView is the CodeView debug information of test PDB files.
+var testCodeView = &CodeViewInfo{
+	Signature: CodeViewSignatureRSDS,
+	GUID: GUID{
+		Data1: 0x6B29FC40,
+		Data2: 0xCA47,
+		Data3: 0x1067,
+		Data4: [8]byte{0xB3, 0x1D, 0x00, 0xDD, 0x01, 0x06, 0x62, 0xDA},
+	},
+	Age:     1,
+	PDBPath: `C:\build\my app#1.pdb`,
+}
+
+// newTestPDB returns the contents of a PDB file matching the given CodeView
+// debug information, holding a PDB stream with an empty named stream map,
+// followed by the given streams (starting at stream 2).
+func newTestPDB(cv *CodeViewInfo, streams ...[]byte) []byte {
+	pdbStream := &bytes.Buffer{}
+	w := func(v interface{}) {
+		binary.Write(pdbStream, binary.LittleEndian, v)
+	}
+	w(PDBVersionVC70)
+	w(uint32(0)) // Date
+	w(cv.Age)
+	w(cv.GUID)
+	// Named stream map; string buffer size, size, capacity, and present and
+	// deleted bit vectors.
+	w([5]uint32{})
+	streams = append([][]byte{nil, pdbStream.Bytes()}, streams...)
+	return newTestMSF(true, streams...).image()
+}
+
+// testStore is an HTTP symbol store serving files by symbol store key,
+// recording the escaped paths of requests.
+type testStore struct {
+	// Map from symbol store key to file contents.
+	files map[string][]byte
+
+	// mu guards reqs.
+	mu sync.Mutex
+	// Escaped paths of requests, in order.
+	reqs []string
+}
+
+// ServeHTTP serves the file of the requested symbol store key.
+func (s *testStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.reqs = append(s.reqs, r.URL.EscapedPath())
+	s.mu.Unlock()
+	data, ok := s.files[strings.TrimPrefix(r.URL.Path, "/")]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
+
+// requests returns the escaped paths of requests to the symbol store.
+func (s *testStore) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.reqs...)
+}
+
+// newTestLocator returns a symbol locator searching the given symbol server,
+// caching into a new temporary directory. The returned function removes the
+// cache directory.
+func newTestLocator(t *testing.T, storeURL string) (*SymbolLocator, string, func()) {
+	cacheDir, err := ioutil.TempDir("", "pdb-cache-")
+	if err != nil {
+		t.Fatalf("unable to create cache directory; %v", err)
+	}
+	symPath, err := ParseSymbolPath(fmt.Sprintf("srv*%s*%s", cacheDir, storeURL))
+	if err != nil {
+		t.Fatalf("unable to parse symbol path; %+v", err)
+	}
+	l := &SymbolLocator{Path: symPath}
+	return l, cacheDir, func() { os.RemoveAll(cacheDir) }
+}
+
+func TestSymstoreKey(t *testing.T) {
+	golden := []struct {
+		pdbPath string
+		want    string
+	}{
+		{pdbPath: `C:\build\app.pdb`, want: "app.pdb/6B29FC40CA471067B31D00DD010662DA1/app.pdb"},
+		{pdbPath: "/build/app.pdb", want: "app.pdb/6B29FC40CA471067B31D00DD010662DA1/app.pdb"},
+		{pdbPath: "app.pdb", want: "app.pdb/6B29FC40CA471067B31D00DD010662DA1/app.pdb"},
+	}
+	for _, g := range golden {
+		cv := *testCodeView
+		cv.PDBPath = g.pdbPath
+		got, err := SymstoreKey(&cv)
+		if err != nil {
+			t.Errorf("%q: unable to compute symbol store key; %+v", g.pdbPath, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%q: symbol store key mismatch; expected %q, got %q", g.pdbPath, g.want, got)
+		}
+	}
+	for _, pdbPath := range []string{"", `C:\build\`, "..", `C:\build\..`, "/build/.", "app\x00.pdb"} {
+		cv := *testCodeView
+		cv.PDBPath = pdbPath
+		if key, err := SymstoreKey(&cv); err == nil {
+			t.Errorf("%q: expected error, got symbol store key %q", pdbPath, key)
+		}
+	}
+}
+
+func TestSymbolLocatorFetch(t *testing.T) {
+	key, err := SymstoreKey(testCodeView)
+	if err != nil {
+		t.Fatalf("unable to compute symbol store key; %+v", err)
+	}
+	pdbData := newTestPDB(testCodeView)
+	store := &testStore{files: map[string][]byte{key: pdbData}}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+	l, cacheDir, cleanup := newTestLocator(t, srv.URL)
+	defer cleanup()
+	// Fetch from symbol server into cache.
+	pdbPath, err := l.Locate(context.Background(), testCodeView)
+	if err != nil {
+		t.Fatalf("unable to locate PDB file; %+v", err)
+	}
+	if want := storePath(cacheDir, key); pdbPath != want {
+		t.Errorf("PDB path mismatch; expected %q, got %q", want, pdbPath)
+	}
+	got, err := ioutil.ReadFile(pdbPath)
+	if err != nil {
+		t.Fatalf("unable to read cached PDB file; %v", err)
+	}
+	if !bytes.Equal(got, pdbData) {
+		t.Errorf("contents of cached PDB file mismatch")
+	}
+	// Path segments of the symbol store key are escaped.
+	reqs := store.requests()
+	wantReq := "/my%20app%231.pdb/6B29FC40CA471067B31D00DD010662DA1/my%20app%231.pdb"
+	if len(reqs) != 1 || reqs[0] != wantReq {
+		t.Errorf("requests mismatch; expected [%q], got %q", wantReq, reqs)
+	}
+	// Locate from cache, without requests to the symbol server.
+	pdbPath, err = l.Locate(context.Background(), testCodeView)
+	if err != nil {
+		t.Fatalf("unable to locate cached PDB file; %+v", err)
+	}
+	if want := storePath(cacheDir, key); pdbPath != want {
+		t.Errorf("PDB path of cache hit mismatch; expected %q, got %q", want, pdbPath)
+	}
+	if n := len(store.requests()); n != 1 {
+		t.Errorf("expected no requests on cache hit, got %d", n-1)
+	}
+}
+
+func TestSymbolLocatorFetchMismatch(t *testing.T) {
+	// The symbol server returns a PDB file of a different age, which is not
+	// stored in the cache.
+	key, err := SymstoreKey(testCodeView)
+	if err != nil {
+		t.Fatalf("unable to compute symbol store key; %+v", err)
+	}
+	cv := *testCodeView
+	cv.Age = 2
+	store := &testStore{files: map[string][]byte{key: newTestPDB(&cv)}}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+	l, cacheDir, cleanup := newTestLocator(t, srv.URL)
+	defer cleanup()
+	if _, err := l.Locate(context.Background(), testCodeView); err == nil {
+		t.Fatalf("expected error for mismatching PDB file, got nil")
+	}
+	if _, err := os.Stat(storePath(cacheDir, key)); !os.IsNotExist(err) {
+		t.Errorf("expected mismatching PDB file not to be cached; %v", err)
+	}
+}
+
+func TestSymbolLocatorFilePtr(t *testing.T) {
+	key, err := SymstoreKey(testCodeView)
+	if err != nil {
+		t.Fatalf("unable to compute symbol store key; %+v", err)
+	}
+	// Local PDB file pointed to by file pointer.
+	srcDir, err := ioutil.TempDir("", "pdb-src-")
+	if err != nil {
+		t.Fatalf("unable to create source directory; %v", err)
+	}
+	defer os.RemoveAll(srcDir)
+	pdbData := newTestPDB(testCodeView)
+	srcPath := filepath.Join(srcDir, "app.pdb")
+	if err := ioutil.WriteFile(srcPath, pdbData, 0644); err != nil {
+		t.Fatalf("unable to write source PDB file; %v", err)
+	}
+	ptrKey := "my app#1.pdb/6B29FC40CA471067B31D00DD010662DA1/file.ptr"
+	store := &testStore{files: map[string][]byte{ptrKey: []byte("PATH:" + srcPath + "\r\n")}}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+	l, cacheDir, cleanup := newTestLocator(t, srv.URL)
+	defer cleanup()
+	pdbPath, err := l.Locate(context.Background(), testCodeView)
+	if err != nil {
+		t.Fatalf("unable to locate PDB file through file pointer; %+v", err)
+	}
+	if want := storePath(cacheDir, key); pdbPath != want {
+		t.Errorf("PDB path mismatch; expected %q, got %q", want, pdbPath)
+	}
+	got, err := ioutil.ReadFile(pdbPath)
+	if err != nil {
+		t.Fatalf("unable to read cached PDB file; %v", err)
+	}
+	if !bytes.Equal(got, pdbData) {
+		t.Errorf("contents of cached PDB file mismatch")
+	}
+	wantReqs := []string{
+		"/my%20app%231.pdb/6B29FC40CA471067B31D00DD010662DA1/my%20app%231.pdb",
+		"/my%20app%231.pdb/6B29FC40CA471067B31D00DD010662DA1/file.ptr",
+	}
+	if reqs := store.requests(); fmt.Sprint(reqs) != fmt.Sprint(wantReqs) {
+		t.Errorf("requests mismatch; expected %q, got %q", wantReqs, reqs)
+	}
+}
+
+func TestSymbolLocatorInvalidName(t *testing.T) {
+	store := &testStore{}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+	l, _, cleanup := newTestLocator(t, srv.URL)
+	defer cleanup()
+	cv := *testCodeView
+	cv.PDBPath = `C:\build\..`
+	if _, err := l.Locate(context.Background(), &cv); err == nil {
+		t.Fatalf("expected error for invalid PDB file name, got nil")
+	}
+	if reqs := store.requests(); len(reqs) != 0 {
+		t.Errorf("expected no requests for invalid PDB file name, got %q", reqs)
+	}
+}
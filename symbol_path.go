@@ -0,0 +1,166 @@
+package pdb
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SymbolPath is a symbol search path, as specified by the _NT_SYMBOL_PATH
+// environment variable of Windows debuggers. Elements are searched in order.
+//
+// ref: https://docs.microsoft.com/en-us/windows-hardware/drivers/debugger/symbol-path
+type SymbolPath []SymbolPathElem
+
+// SymbolPathElem is an element of a symbol search path.
+type SymbolPathElem struct {
+	// Kind of symbol path element.
+	Kind SymbolPathKind
+	// Local directory (SymbolPathDir and SymbolPathCache).
+	Dir string
+	// Local cache directories (downstream stores) of the symbol server, in
+	// search order (SymbolPathServer). An empty cache directory denotes the
+	// default cache directory.
+	Caches []string
+	// Symbol store of the symbol server (SymbolPathServer); either an HTTP(S)
+	// URL or a directory laid out as a symbol store (e.g. a UNC path).
+	Store string
+}
+
+//go:generate stringer -linecomment -type SymbolPathKind
+
+// SymbolPathKind specifies the kind of a symbol path element.
+type SymbolPathKind uint8
+
+// Symbol path element kinds.
+const (
+	// Plain directory, searched both as a flat directory and as a symbol
+	// store (e.g. "C:\symbols").
+	SymbolPathDir SymbolPathKind = iota + 1 // dir
+	// Symbol server with optional local caches (e.g.
+	// "srv*C:\cache*https://msdl.microsoft.com/download/symbols").
+	SymbolPathServer // srv
+	// Local cache directory of the symbol files located by subsequent elements
+	// (e.g. "cache*C:\cache").
+	SymbolPathCache // cache
+)
+
+// ParseSymbolPath parses the given symbol search path, consisting of elements
+// separated by semicolons. The following elements are supported.
+//
+//    DIR                       plain directory
+//    srv*[CACHE*]...STORE      symbol server with local caches
+//    symsrv*DLL*[CACHE*]...STORE
+//    cache*[DIR]               local cache of subsequent elements
+//
+// Prefixes are case insensitive. Empty elements are ignored.
+func ParseSymbolPath(s string) (SymbolPath, error) {
+	var symPath SymbolPath
+	for _, elem := range strings.Split(s, ";") {
+		elem = strings.TrimSpace(elem)
+		if len(elem) == 0 {
+			continue
+		}
+		parts := strings.Split(elem, "*")
+		switch prefix := strings.ToLower(parts[0]); {
+		case len(parts) == 1:
+			symPath = append(symPath, SymbolPathElem{Kind: SymbolPathDir, Dir: elem})
+		case prefix == "srv" || prefix == "symsrv":
+			parts = parts[1:]
+			if prefix == "symsrv" {
+				// Skip symbol server DLL (e.g. symsrv.dll).
+				parts = parts[1:]
+			}
+			if len(parts) == 0 || len(parts[len(parts)-1]) == 0 {
+				return nil, errors.Errorf("invalid symbol server element %q; missing symbol store", elem)
+			}
+			symPath = append(symPath, SymbolPathElem{
+				Kind:   SymbolPathServer,
+				Caches: parts[:len(parts)-1],
+				Store:  parts[len(parts)-1],
+			})
+		case prefix == "cache":
+			if len(parts) > 2 {
+				return nil, errors.Errorf("invalid cache element %q; expected cache*DIR", elem)
+			}
+			symPath = append(symPath, SymbolPathElem{Kind: SymbolPathCache, Dir: parts[1]})
+		default:
+			return nil, errors.Errorf("invalid symbol path element %q; unknown prefix %q", elem, parts[0])
+		}
+	}
+	return symPath, nil
+}
+
+// String returns the string representation of the symbol search path.
+func (symPath SymbolPath) String() string {
+	var elems []string
+	for _, elem := range symPath {
+		elems = append(elems, elem.String())
+	}
+	return strings.Join(elems, ";")
+}
+
+// String returns the string representation of the symbol path element.
+func (elem SymbolPathElem) String() string {
+	switch elem.Kind {
+	case SymbolPathDir:
+		return elem.Dir
+	case SymbolPathServer:
+		parts := append([]string{"srv"}, elem.Caches...)
+		return strings.Join(append(parts, elem.Store), "*")
+	case SymbolPathCache:
+		return "cache*" + elem.Dir
+	}
+	return fmt.Sprintf("<invalid symbol path element kind %d>", elem.Kind)
+}
+
+// SymstoreKey returns the relative path of the PDB file identified by the
+// given CodeView debug information within a symbol store; i.e.
+// "name.pdb/<GUID><AGE>/name.pdb" for RSDS records (with GUID in symbol store
+// form and age in uppercase hexadecimal), and "name.pdb/<TIMESTAMP><AGE>/name.pdb"
+// for NB10 records. Forward slashes are used as path separator.
+//
+// An error is returned if the file name of the PDB path is not a valid path
+// segment (e.g. empty or "..").
+func SymstoreKey(cv *CodeViewInfo) (string, error) {
+	name := pdbBaseName(cv.PDBPath)
+	if err := validatePDBName(name); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return path.Join(name, SymstoreID(cv), name), nil
+}
+
+// SymstoreID returns the index directory name of the PDB file identified by the
+// given CodeView debug information within a symbol store (e.g.
+// "6B29FC40CA471067B31D00DD010662DA1").
+func SymstoreID(cv *CodeViewInfo) string {
+	if cv.Signature == CodeViewSignatureNB10 {
+		return fmt.Sprintf("%08X%X", cv.TimeStamp, cv.Age)
+	}
+	return fmt.Sprintf("%s%X", cv.GUID.SymstoreString(), cv.Age)
+}
+
+// pdbBaseName returns the file name of the given PDB path, as recorded by the
+// linker using either Windows or Unix path separators.
+func pdbBaseName(pdbPath string) string {
+	if i := strings.LastIndexAny(pdbPath, `\/`); i != -1 {
+		pdbPath = pdbPath[i+1:]
+	}
+	return pdbPath
+}
+
+// validatePDBName validates the given PDB file name for use as a path segment
+// of symbol store keys. The PDB path is read from the CodeView debug
+// information of images, and may therefore not be trusted.
+func validatePDBName(name string) error {
+	switch name {
+	case "", ".", "..":
+		return errors.Errorf("invalid PDB file name %q", name)
+	}
+	if strings.ContainsAny(name, "/\\\x00") {
+		return errors.Errorf("invalid PDB file name %q; contains path separator or NUL", name)
+	}
+	return nil
+}
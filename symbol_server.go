@@ -0,0 +1,189 @@
+package pdb
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SymbolServer is an HTTP handler serving the PDB files of a directory tree in
+// the URL layout of symbol stores (i.e. "/name.pdb/<ID>/name.pdb"), as
+// requested by Windows debuggers through symbol server path elements (e.g.
+// "srv*C:\cache*http://example.com/symbols").
+//
+// Requests for PDB files not present in the index are answered with 404 Not
+// Found, so that debuggers continue searching along their symbol path.
+type SymbolServer struct {
+	// Root directory of the PDB files.
+	Root string
+	// Serve file pointers (file.ptr) referring to the local path of PDB files
+	// (e.g. for directories shared with debuggers through UNC paths); if false,
+	// requests for file pointers are answered with 404 Not Found.
+	FilePtr bool
+	// Logger of warning messages (e.g. unparsable PDB files); no warnings are
+	// logged if nil.
+	Warn *log.Logger
+
+	// mu guards index.
+	mu sync.RWMutex
+	// Map from lowercase symbol store key to path of PDB file.
+	index map[string]string
+}
+
+// NewSymbolServer returns a new symbol server serving the PDB files of the
+// directory tree rooted at root, indexing the PDB files.
+func NewSymbolServer(root string) (*SymbolServer, error) {
+	s := &SymbolServer{Root: root}
+	if err := s.Reindex(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return s, nil
+}
+
+// Reindex indexes the PDB files of the directory tree of the symbol server,
+// reading the GUID (or time stamp) and age of each PDB file. Files which fail
+// to parse are skipped, logging a warning.
+func (s *SymbolServer) Reindex() error {
+	warn := s.Warn
+	if warn == nil {
+		warn = discard
+	}
+	index := make(map[string]string)
+	err := filepath.Walk(s.Root, func(pdbPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(pdbPath), ".pdb") {
+			return nil
+		}
+		id, err := symstoreIDOfPDB(pdbPath)
+		if err != nil {
+			warn.Printf("unable to index %q: %v", pdbPath, err)
+			return nil
+		}
+		name := filepath.Base(pdbPath)
+		key := strings.ToLower(path.Join(name, id, name))
+		if prev, ok := index[key]; ok {
+			warn.Printf("skipping %q; symbol store key %q already used by %q", pdbPath, key, prev)
+			return nil
+		}
+		index[key] = pdbPath
+		return nil
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	s.mu.Lock()
+	s.index = index
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of indexed PDB files.
+func (s *SymbolServer) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.index)
+}
+
+// symstoreIDOfPDB returns the index directory name of the given PDB file within
+// a symbol store; i.e. its GUID (or time stamp) followed by its age, which is
+// taken from the DBI stream if present, as recorded by the images of the PDB.
+//
+// Only the PDB stream and the DBI stream header are read, as PDB files may be
+// several gigabytes in size.
+func symstoreIDOfPDB(pdbPath string) (string, error) {
+	file, err := OpenMmap(pdbPath, nil)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer file.Close()
+	pdbStream, err := file.PDBInfo()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	cv := &CodeViewInfo{
+		Signature: CodeViewSignatureRSDS,
+		GUID:      pdbStream.Hdr.UniqueID,
+		Age:       pdbStream.Hdr.Age,
+	}
+	if pdbStream.Hdr.Version < PDBVersionVC70Deprecated {
+		cv.Signature = CodeViewSignatureNB10
+		cv.TimeStamp = uint32(pdbStream.Hdr.Date.Unix())
+	}
+	// DBI streams with old-style headers record no age; the age of the PDB
+	// stream is used instead.
+	if int(StreamIDDBIStream) < len(file.StreamTbl.StreamInfos) && !file.StreamTbl.StreamInfos[StreamIDDBIStream].IsNil() {
+		sr, err := file.StreamReader(StreamNumber(StreamIDDBIStream))
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		dbiHdr, err := file.parseDBIStreamHeader(sr)
+		switch {
+		case err == nil:
+			cv.Age = dbiHdr.Age
+		case !isUnsupported(err):
+			return "", errors.WithStack(sr.formatError(err))
+		}
+	}
+	return SymstoreID(cv), nil
+}
+
+// ServeHTTP serves the PDB file (or file pointer) of the given symbol store
+// request.
+func (s *SymbolServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	// Symbol store keys are case insensitive.
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 {
+		http.NotFound(w, r)
+		return
+	}
+	name, id, fileName := parts[0], parts[1], parts[2]
+	key := strings.ToLower(path.Join(name, id, name))
+	s.mu.RLock()
+	pdbPath, ok := s.index[key]
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case strings.EqualFold(fileName, name):
+		f, err := os.Open(pdbPath)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		http.ServeContent(w, r, fileName, fi.ModTime(), f)
+	case s.FilePtr && strings.EqualFold(fileName, "file.ptr"):
+		absPath, err := filepath.Abs(pdbPath)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "PATH:%s", absPath)
+	default:
+		// Compressed files (e.g. name.pd_) are not served.
+		http.NotFound(w, r)
+	}
+}
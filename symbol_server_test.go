@@ -0,0 +1,49 @@
+package pdb
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymbolServer(t *testing.T) {
+	root, err := ioutil.TempDir("", "pdb-symbols-")
+	if err != nil {
+		t.Fatalf("unable to create root directory; %v", err)
+	}
+	defer os.RemoveAll(root)
+	pdbData := newTestPDB(testCodeView)
+	if err := ioutil.WriteFile(filepath.Join(root, "my app#1.pdb"), pdbData, 0644); err != nil {
+		t.Fatalf("unable to write PDB file; %v", err)
+	}
+	// Files which fail to parse are skipped.
+	if err := ioutil.WriteFile(filepath.Join(root, "invalid.pdb"), []byte("invalid"), 0644); err != nil {
+		t.Fatalf("unable to write PDB file; %v", err)
+	}
+	s, err := NewSymbolServer(root)
+	if err != nil {
+		t.Fatalf("unable to index PDB files; %+v", err)
+	}
+	if got, want := s.Len(), 1; got != want {
+		t.Fatalf("number of indexed PDB files mismatch; expected %d, got %d", want, got)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	l, _, cleanup := newTestLocator(t, srv.URL)
+	defer cleanup()
+	pdbPath, err := l.Locate(context.Background(), testCodeView)
+	if err != nil {
+		t.Fatalf("unable to locate PDB file; %+v", err)
+	}
+	got, err := ioutil.ReadFile(pdbPath)
+	if err != nil {
+		t.Fatalf("unable to read cached PDB file; %v", err)
+	}
+	if !bytes.Equal(got, pdbData) {
+		t.Errorf("contents of cached PDB file mismatch")
+	}
+}
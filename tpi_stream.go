@@ -3,66 +3,201 @@ package pdb
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 
-	"github.com/kr/pretty"
 	"github.com/pkg/errors"
 )
 
 // TPIStream records information about types used in the program. Types are
-// referenced by their type index from other parts of the PDB.
+// referenced by their type index from other parts of the PDB. The IPI stream
+// (stream 4) shares the format of the TPI stream.
 //
 // ref: https://llvm.org/docs/PDB/TpiStream.html
 type TPIStream struct {
-	// TPI stream header.
-	Hdr *TPIStreamHeader16
-	// Type records.
+	streamMeta
+	// TPI stream header; converted from the 16-bit TPI stream header for TPI
+	// versions prior to V 5.0.
+	Hdr *TPIStreamHeader
+	// TPI stream header with 16-bit type indices, as stored in TPI streams prior
+	// to V 5.0; or nil.
+	Hdr16 *TPIStreamHeader16
+	// Type records. The contents of type records are sliced from the type
+	// records data of the stream without copying; for PDB files opened with
+	// OpenMmap, they alias the memory-mapped file contents, and are only valid
+	// until File.Close.
 	Types []TypeRecord
 }
 
-// parseTPIStream parses the given TPI stream.
-func (file *File) parseTPIStream(r io.Reader) (*TPIStream, error) {
-	// Parse TPI stream header.
+// parseTPIStream parses the given TPI stream, reading from r.
+func (file *File) parseTPIStream(r *StreamReader) (*TPIStream, error) {
+	// Parse TPI stream header; the header format is selected by the TPI version.
 	tpiStream := &TPIStream{}
-	hdr, err := file.parseTPIStreamHeader16(r)
-	if err != nil {
+	var version TPIVersion
+	if err := binary.Read(io.NewSectionReader(r, 0, 4), binary.LittleEndian, &version); err != nil {
 		return nil, errors.WithStack(err)
 	}
+	var hdr *TPIStreamHeader
+	switch version {
+	case TPIVersionV40, TPIVersionV41:
+		hdr16, err := file.parseTPIStreamHeader16(r)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		tpiStream.Hdr16 = hdr16
+		// Skip padding.
+		hdrSize := int64(binary.Size(hdr16))
+		npad := hdrSize % 4
+		if _, err := io.CopyN(ioutil.Discard, r, npad); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		hdr = hdr16.header(uint32(hdrSize + npad))
+	case TPIVersionV50, TPIVersionV70, TPIVersionV80:
+		var err error
+		if hdr, err = file.parseTPIStreamHeader(r); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Skip unknown header fields.
+		if int64(hdr.HeaderSize) > r.Size() {
+			return nil, errors.Errorf("invalid size of TPI stream header; expected <= %d, got %d", r.Size(), hdr.HeaderSize)
+		}
+		if _, err := r.Seek(int64(hdr.HeaderSize), io.SeekStart); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	default:
+		return nil, unsupportedf("support for TPI version %v not yet implemented", version)
+	}
 	tpiStream.Hdr = hdr
-	// Skip padding.
-	buf := &bytes.Buffer{}
-	binary.Write(buf, binary.LittleEndian, &TPIStreamHeader16{})
-	hdrSize := int64(buf.Len())
-	dbg.Println("   hdrSize:", hdrSize)
-	npad := hdrSize % 4
-	dbg.Println("   npad:", npad)
-	if _, err := io.CopyN(ioutil.Discard, r, npad); err != nil {
+	// Parse type records.
+	typeRecordsSize := int64(hdr.TypeRecordsSize)
+	if rem := r.Size() - r.off; typeRecordsSize < 0 || typeRecordsSize > rem {
+		err := errors.Errorf("invalid size of type records; expected >= 0 and <= %d, got %d", rem, hdr.TypeRecordsSize)
+		if !file.opts.Recover {
+			return nil, errors.WithStack(err)
+		}
+		file.addDiagnostic(SeverityWarning, r.formatError(err))
+		typeRecordsSize = rem
+	}
+	// Type records data is sliced directly from memory-mapped files.
+	typeRecordsData, err := r.Bytes(r.off, typeRecordsSize)
+	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	// Parse type records.
-	typeRecordsData := make([]byte, hdr.TypeRecordsSize)
-	if _, err := io.ReadFull(r, typeRecordsData); err != nil {
+	if _, err := r.Seek(typeRecordsSize, io.SeekCurrent); err != nil {
 		return nil, errors.WithStack(err)
 	}
-	dbg.Print("type records data:\n", hex.Dump(typeRecordsData))
+	file.dbg.Printf("parseTPIStream: %d bytes of type records", len(typeRecordsData))
+	if hdr.TypeIndexEnd < hdr.TypeIndexBegin {
+		err := errors.Errorf("invalid type index range [%d, %d)", uint32(hdr.TypeIndexBegin), uint32(hdr.TypeIndexEnd))
+		if !file.opts.Recover {
+			return nil, errors.WithStack(err)
+		}
+		// Keep the type records data as raw bytes, as the number of type
+		// records is unknown.
+		file.addDiagnostic(SeverityError, r.formatError(err))
+		tpiStream.Types = []TypeRecord{{Raw: typeRecordsData}}
+		return tpiStream, nil
+	}
 	rr := bytes.NewReader(typeRecordsData)
-	ntypes := int(hdr.LastTypeID - hdr.FirstTypeID) // TODO: handle 0 case.
-	tpiStream.Types = make([]TypeRecord, ntypes)
+	typeRecordsOff := int64(hdr.HeaderSize) // offset of type records within stream.
+	ntypes := int(hdr.TypeIndexEnd - hdr.TypeIndexBegin)
+	// Each type record is at least 4 bytes in size (record size and kind).
+	if max := len(typeRecordsData) / 4; ntypes > max {
+		err := errors.Errorf("invalid number of type records %d; type records of %d bytes hold at most %d records", ntypes, len(typeRecordsData), max)
+		if !file.opts.Recover {
+			return nil, errors.WithStack(err)
+		}
+		file.addDiagnostic(SeverityWarning, r.formatError(err))
+		ntypes = max
+	}
+	tpiStream.Types = make([]TypeRecord, 0, ntypes)
 	for i := 0; i < ntypes; i++ {
-		t, err := file.parseTypeRecord(rr)
-		if err != nil {
+		recordStart := len(typeRecordsData) - rr.Len() // offset of record within type records.
+		recordOff := typeRecordsOff + int64(recordStart)
+		if err := r.ctxErr(); err != nil {
 			return nil, errors.WithStack(err)
 		}
-		pretty.Println("t:", t)
-		tpiStream.Types[i] = t
+		if i%progressInterval == 0 {
+			file.progress(r, recordOff, i, false)
+		}
+		t, err := file.parseTypeRecord(rr, typeRecordsData)
+		if err != nil {
+			var kind TypeRecordKind
+			if t.Hdr != nil {
+				kind = t.Hdr.RecordKind
+			}
+			recordErr := newRecordError(i, kind, recordOff, err)
+			if !file.opts.Recover {
+				return nil, errors.WithStack(recordErr)
+			}
+			// Keep the remaining type records data as raw bytes, as record
+			// boundaries are unknown past an undecodable record.
+			file.addDiagnostic(SeverityError, r.formatError(recordErr))
+			t.Raw = typeRecordsData[recordStart:]
+			tpiStream.Types = append(tpiStream.Types, t)
+			break
+		}
+		tpiStream.Types = append(tpiStream.Types, t)
 	}
 	return tpiStream, nil
 }
 
-// TPIStreamHeader16 is a header of the TPI stream with 16-bit type IDs.
+// TPIStreamHeader is a header of the TPI stream with 32-bit type indices, as
+// used by TPI versions V 5.0 and later.
+//
+// ref: HDR in PDB/dbi/tpi.h
+// ref: https://llvm.org/docs/PDB/TpiStream.html#tpi-header
+type TPIStreamHeader struct {
+	// TPI version.
+	Version TPIVersion
+	// Size in bytes of the header; type records data follows the header.
+	HeaderSize uint32
+	// First type index, inclusive; type index of first type record in the TPI
+	// stream.
+	TypeIndexBegin TypeIndex
+	// Last type index, exclusive.
+	TypeIndexEnd TypeIndex
+	// Size in bytes of type records data following header.
+	TypeRecordsSize int32
+	// Index of TPI hash stream.
+	HashStreamNum StreamNumber
+	// Index of auxiliary TPI hash stream; or NilStreamNum if not present.
+	HashAuxStreamNum StreamNumber
+	// Size in bytes of hash keys.
+	HashKeySize uint32
+	// Number of hash buckets.
+	NumHashBuckets uint32
+	// Offset of hash values buffer within hash stream.
+	HashValueBufferOffset int32
+	// Size in bytes of hash values buffer.
+	HashValueBufferLength int32
+	// Offset of type index offsets buffer within hash stream; pairs of type
+	// index and offset of type record within type records data.
+	IndexOffsetBufferOffset int32
+	// Size in bytes of type index offsets buffer.
+	IndexOffsetBufferLength int32
+	// Offset of hash adjustment buffer within hash stream.
+	HashAdjBufferOffset int32
+	// Size in bytes of hash adjustment buffer.
+	HashAdjBufferLength int32
+}
+
+// parseTPIStreamHeader parses the given TPI stream header with 32-bit type
+// indices.
+func (file *File) parseTPIStreamHeader(r io.Reader) (*TPIStreamHeader, error) {
+	hdr := &TPIStreamHeader{}
+	if err := binary.Read(r, binary.LittleEndian, hdr); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if hdrSize := uint32(binary.Size(hdr)); hdr.HeaderSize < hdrSize {
+		return nil, errors.Errorf("invalid size of TPI stream header; expected >= %d, got %d", hdrSize, hdr.HeaderSize)
+	}
+	return hdr, nil
+}
+
+// TPIStreamHeader16 is a header of the TPI stream with 16-bit type IDs, as used
+// by TPI versions prior to V 5.0.
 //
 // ref: HDR_16t in PDB/dbi/tpi.h
 type TPIStreamHeader16 struct {
@@ -125,6 +260,36 @@ func (file *File) parseTPIStreamHeader16(r io.Reader) (*TPIStreamHeader16, error
 	return hdr, nil
 }
 
+// header returns the TPI stream header with 32-bit type indices corresponding
+// to the given 16-bit TPI stream header, which is of the given size in bytes
+// including padding.
+func (hdr16 *TPIStreamHeader16) header(hdrSize uint32) *TPIStreamHeader {
+	return &TPIStreamHeader{
+		Version:          hdr16.Version,
+		HeaderSize:       hdrSize,
+		TypeIndexBegin:   TypeIndex(hdr16.FirstTypeID),
+		TypeIndexEnd:     TypeIndex(hdr16.LastTypeID),
+		TypeRecordsSize:  hdr16.TypeRecordsSize,
+		HashStreamNum:    hdr16.HashStreamNum,
+		HashAuxStreamNum: NilStreamNum,
+	}
+}
+
+// TypeIndex is a 32-bit type index which uniquely identifies a type of the
+// PDB. Type indices below Hdr.TypeIndexBegin denote basic types, and are
+// decomposed as TypeID16.
+//
+// ref: https://llvm.org/docs/PDB/TpiStream.html#type-indices
+type TypeIndex uint32
+
+// String returns the string representation of the given type index.
+func (typeIndex TypeIndex) String() string {
+	if typeIndex >= 0x1000 {
+		return fmt.Sprintf("TypeIndex(%d)", uint32(typeIndex))
+	}
+	return TypeID16(typeIndex).String()
+}
+
 // TypeID16 is a 16-bit type index which uniquely identifies a type of the PDB.
 //
 // Any typeID >= Hdr.FirstTypeID is persumed to come from the corresponding TPI
@@ -0,0 +1,167 @@
+package pdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testTypeRecords is the type records data of test TPI streams; two type
+// records of 8 and 4 bytes.
+var testTypeRecords = []byte{
+	0x06, 0x00, 0x01, 0x10, 0x74, 0x00, 0x00, 0x00, // LF_MODIFIER
+	0x02, 0x00, 0x03, 0x00, // empty record of kind 0x0003
+}
+
+// newTestTPIStream returns the contents of a TPI stream with 32-bit type
+// indices, with the given header followed by padding up to hdr.HeaderSize and
+// the test type records.
+func newTestTPIStream(hdr *TPIStreamHeader) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, hdr)
+	for buf.Len() < int(hdr.HeaderSize) {
+		buf.WriteByte(0xFF)
+	}
+	buf.Write(testTypeRecords)
+	return buf.Bytes()
+}
+
+// parseTestTPIStream returns the TPI stream (stream 2) of a PDB file holding
+// the given TPI stream contents.
+func parseTestTPIStream(t *testing.T, tpi []byte, opts *ParseOptions) (*File, Stream, error) {
+	buf := newTestPDB(testCodeView, tpi)
+	file, err := Open(bytes.NewReader(buf), int64(len(buf)), opts)
+	if err != nil {
+		t.Fatalf("unable to open PDB file; %+v", err)
+	}
+	stream, err := file.stream(StreamNumber(StreamIDTPIStream))
+	return file, stream, err
+}
+
+func TestParseTPIStream(t *testing.T) {
+	v80 := &TPIStreamHeader{
+		Version:          TPIVersionV80,
+		HeaderSize:       56,
+		TypeIndexBegin:   0x1000,
+		TypeIndexEnd:     0x1002,
+		TypeRecordsSize:  int32(len(testTypeRecords)),
+		HashStreamNum:    NilStreamNum,
+		HashAuxStreamNum: NilStreamNum,
+	}
+	// Unknown header fields are skipped, up to the header size.
+	v80Large := *v80
+	v80Large.HeaderSize = 64
+	// TPI stream header with 16-bit type indices; 14 bytes followed by 2 bytes
+	// of padding.
+	v40 := &bytes.Buffer{}
+	binary.Write(v40, binary.LittleEndian, &TPIStreamHeader16{
+		Version:         TPIVersionV40,
+		FirstTypeID:     0x1000,
+		LastTypeID:      0x1002,
+		TypeRecordsSize: int32(len(testTypeRecords)),
+		HashStreamNum:   5,
+	})
+	v40.Write([]byte{0xFF, 0xFF})
+	v40.Write(testTypeRecords)
+	golden := []struct {
+		name    string
+		tpi     []byte
+		want    TPIStreamHeader
+		want16  bool
+		wantErr bool
+	}{
+		{name: "V 8.0", tpi: newTestTPIStream(v80), want: *v80},
+		{name: "V 8.0 with unknown header fields", tpi: newTestTPIStream(&v80Large), want: v80Large},
+		{
+			name: "V 4.0",
+			tpi:  v40.Bytes(),
+			want: TPIStreamHeader{
+				Version:          TPIVersionV40,
+				HeaderSize:       16,
+				TypeIndexBegin:   0x1000,
+				TypeIndexEnd:     0x1002,
+				TypeRecordsSize:  int32(len(testTypeRecords)),
+				HashStreamNum:    5,
+				HashAuxStreamNum: NilStreamNum,
+			},
+			want16: true,
+		},
+	}
+	for _, g := range golden {
+		_, stream, err := parseTestTPIStream(t, g.tpi, nil)
+		if err != nil {
+			t.Errorf("%s: unable to parse TPI stream; %+v", g.name, err)
+			continue
+		}
+		tpiStream, ok := stream.(*TPIStream)
+		if !ok {
+			t.Errorf("%s: expected *TPIStream, got %T", g.name, stream)
+			continue
+		}
+		if *tpiStream.Hdr != g.want {
+			t.Errorf("%s: TPI stream header mismatch; expected %+v, got %+v", g.name, g.want, *tpiStream.Hdr)
+		}
+		if got16 := tpiStream.Hdr16 != nil; got16 != g.want16 {
+			t.Errorf("%s: presence of 16-bit TPI stream header mismatch; expected %v, got %v", g.name, g.want16, got16)
+		}
+		if len(tpiStream.Types) != 2 {
+			t.Errorf("%s: number of type records mismatch; expected 2, got %d", g.name, len(tpiStream.Types))
+			continue
+		}
+		if got, want := tpiStream.Types[0].Hdr.RecordKind, TypeRecordKind(0x1001); got != want {
+			t.Errorf("%s: type record kind mismatch; expected 0x%04X, got 0x%04X", g.name, uint16(want), uint16(got))
+		}
+	}
+}
+
+func TestParseTPIStreamV50Interim(t *testing.T) {
+	// TPI streams of version V 5.0 - interim are kept as raw streams.
+	hdr := &TPIStreamHeader{
+		Version:    TPIVersionV50Interim,
+		HeaderSize: 56,
+	}
+	file, stream, err := parseTestTPIStream(t, newTestTPIStream(hdr), &ParseOptions{Recover: true})
+	if err != nil {
+		t.Fatalf("unable to parse TPI stream; %+v", err)
+	}
+	if _, ok := stream.(*RawStream); !ok {
+		t.Errorf("expected *RawStream, got %T", stream)
+	}
+	if n := len(file.Diagnostics()); n != 1 {
+		t.Errorf("number of diagnostics mismatch; expected 1, got %d", n)
+	}
+	if _, _, err := parseTestTPIStream(t, newTestTPIStream(hdr), &ParseOptions{Strict: true}); err == nil {
+		t.Errorf("expected error for unsupported TPI version in strict mode, got nil")
+	}
+}
+
+func TestParseTPIStreamInvalidRange(t *testing.T) {
+	hdr := &TPIStreamHeader{
+		Version:          TPIVersionV80,
+		HeaderSize:       56,
+		TypeIndexBegin:   0x1002,
+		TypeIndexEnd:     0x1000,
+		TypeRecordsSize:  int32(len(testTypeRecords)),
+		HashStreamNum:    NilStreamNum,
+		HashAuxStreamNum: NilStreamNum,
+	}
+	tpi := newTestTPIStream(hdr)
+	if _, _, err := parseTestTPIStream(t, tpi, nil); err == nil {
+		t.Errorf("expected error for invalid type index range, got nil")
+	}
+	// The type records data is kept as raw bytes in recovery mode.
+	file, stream, err := parseTestTPIStream(t, tpi, &ParseOptions{Recover: true})
+	if err != nil {
+		t.Fatalf("unable to parse TPI stream in recovery mode; %+v", err)
+	}
+	tpiStream, ok := stream.(*TPIStream)
+	if !ok {
+		t.Fatalf("expected *TPIStream, got %T", stream)
+	}
+	if len(tpiStream.Types) != 1 || !bytes.Equal(tpiStream.Types[0].Raw, testTypeRecords) {
+		t.Errorf("expected type records data kept as one raw type record, got %d type records", len(tpiStream.Types))
+	}
+	if n := len(file.Diagnostics()); n != 1 {
+		t.Errorf("number of diagnostics mismatch; expected 1, got %d", n)
+	}
+}
@@ -1,6 +1,7 @@
 package pdb
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 
@@ -11,10 +12,18 @@ import (
 type TypeRecord struct {
 	Hdr *TypeRecordHeader
 	// TODO: add fields. potentially turn into an interface?
+
+	// Raw contents of undecodable type record data, as kept in recovery mode
+	// (see ParseOptions.Recover); nil if decoded. Raw aliases the type records
+	// data of the TPI stream (see TPIStream.Types).
+	Raw []byte
 }
 
-// parseTypeRecord parses the given type record, reading from r.
-func (file *File) parseTypeRecord(r io.Reader) (TypeRecord, error) {
+// parseTypeRecord parses the given type record, reading from r, which reads
+// the given type records data. The type record body is sliced from data
+// without copying. The type record header is returned on failure to read the
+// type record body.
+func (file *File) parseTypeRecord(r *bytes.Reader, data []byte) (TypeRecord, error) {
 	// TypeRecordHeader.
 	typ := TypeRecord{}
 	hdr, err := file.parseTypeRecordHeader(r)
@@ -23,10 +32,17 @@ func (file *File) parseTypeRecord(r io.Reader) (TypeRecord, error) {
 	}
 	typ.Hdr = hdr
 	// Read type record body contents.
-	bodySize := typ.Hdr.RecordSize - 2
-	hdr.body = make([]byte, bodySize)
-	if _, err := io.ReadFull(r, hdr.body); err != nil {
-		return TypeRecord{}, nil
+	if typ.Hdr.RecordSize < 2 {
+		return typ, errors.Errorf("invalid type record size %d; expected >= 2", typ.Hdr.RecordSize)
+	}
+	bodySize := int(typ.Hdr.RecordSize - 2)
+	if bodySize > r.Len() {
+		return typ, errors.WithStack(io.ErrUnexpectedEOF)
+	}
+	start := len(data) - r.Len() // offset of type record body within data.
+	hdr.body = data[start : start+bodySize : start+bodySize]
+	if _, err := r.Seek(int64(bodySize), io.SeekCurrent); err != nil {
+		return typ, errors.WithStack(err)
 	}
 	// TODO: parse type record body.
 	return typ, nil
@@ -39,6 +55,7 @@ type TypeRecordHeader struct {
 	RecordSize uint16
 	RecordKind TypeRecordKind
 
+	// Type record body; aliases the type records data of the TPI stream.
 	body []byte // TODO: remove.
 }
 